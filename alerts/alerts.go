@@ -0,0 +1,145 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// queueSize is how many alerts can wait to be posted before new ones are dropped
+const queueSize = 100
+
+// Alert is something worth telling someone about, posted as JSON to every webhook
+// Alerts with the same Key aren't sent again until the cooldown has passed
+type Alert struct {
+	Kind    string      `json:"kind"`
+	Key     string      `json:"key"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+	Time    time.Time   `json:"time"`
+}
+
+// Dispatcher posts alerts to webhooks in the background, it's a lifecycle component
+type Dispatcher struct {
+	webhooks []string
+	cooldown time.Duration
+	client   http.Client
+
+	lock     sync.Mutex
+	lastSent map[string]time.Time
+	queue    chan Alert
+	done     chan struct{}
+}
+
+// NewDispatcher makes a dispatcher for the webhooks that sends each key at most once per cooldown
+func NewDispatcher(webhooks []string, cooldown time.Duration, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		webhooks: webhooks,
+		cooldown: cooldown,
+		client:   http.Client{Timeout: timeout},
+		lastSent: map[string]time.Time{},
+	}
+}
+
+// Name is what the dispatcher is called in logs
+func (dispatcher *Dispatcher) Name() string {
+	return "alert dispatcher"
+}
+
+// Start begins posting queued alerts
+func (dispatcher *Dispatcher) Start() error {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+	if dispatcher.queue != nil {
+		return errors.New("the alert dispatcher is already running")
+	}
+	dispatcher.queue = make(chan Alert, queueSize)
+	dispatcher.done = make(chan struct{})
+
+	go func(queue chan Alert, done chan struct{}) {
+		defer close(done)
+		for alert := range queue {
+			dispatcher.post(alert)
+		}
+	}(dispatcher.queue, dispatcher.done)
+	return nil
+}
+
+// Stop posts what's already queued and waits for it, giving up when ctx is done
+func (dispatcher *Dispatcher) Stop(ctx context.Context) error {
+	dispatcher.lock.Lock()
+	queue, done := dispatcher.queue, dispatcher.done
+	dispatcher.queue = nil
+	dispatcher.lock.Unlock()
+	if queue == nil {
+		return nil
+	}
+
+	close(queue)
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Send queues an alert unless its key was sent within the cooldown, the dispatcher isn't running or the queue is full
+// It reports whether the alert was queued
+func (dispatcher *Dispatcher) Send(alert Alert) bool {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+	if dispatcher.queue == nil {
+		return false
+	}
+	if alert.Time.IsZero() {
+		alert.Time = time.Now().UTC()
+	}
+	if sent, ok := dispatcher.lastSent[alert.Key]; ok && alert.Time.Sub(sent) < dispatcher.cooldown {
+		return false
+	}
+
+	select {
+	case dispatcher.queue <- alert:
+		dispatcher.lastSent[alert.Key] = alert.Time
+		return true
+	default:
+		lib.Logger(context.Background()).Warn("Alert queue is full, dropping an alert", "kind", alert.Kind, "key", alert.Key)
+		return false
+	}
+}
+
+// post sends an alert to every webhook, a webhook that fails is logged and the rest still get it
+// Webhooks are logged by their position since their URLs are often secrets
+func (dispatcher *Dispatcher) post(alert Alert) {
+	body, jsonErr := json.Marshal(alert)
+	if jsonErr != nil {
+		lib.Logger(context.Background()).Error("Problem encoding an alert", "kind", alert.Kind, "error", jsonErr.Error())
+		return
+	}
+
+	for i, webhook := range dispatcher.webhooks {
+		res, postErr := dispatcher.client.Post(webhook, "application/json", bytes.NewReader(body))
+		if urlErr, ok := postErr.(*url.Error); ok {
+			postErr = urlErr.Err
+		}
+		if postErr == nil {
+			res.Body.Close()
+			if res.StatusCode >= 400 {
+				postErr = errors.Errorf("webhook answered %s", res.Status)
+			}
+		}
+		if postErr != nil {
+			lib.Logger(context.Background()).Warn("Problem posting an alert", "webhook", i, "kind", alert.Kind, "error", postErr.Error())
+			continue
+		}
+		lib.Logger(context.Background()).Info("Posted an alert", "webhook", i, "kind", alert.Kind, "key", alert.Key)
+	}
+}
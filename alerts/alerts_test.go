@@ -0,0 +1,68 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type webhook struct {
+	lock     sync.Mutex
+	received []Alert
+}
+
+func (hook *webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	alert := Alert{}
+	json.NewDecoder(r.Body).Decode(&alert)
+	hook.lock.Lock()
+	hook.received = append(hook.received, alert)
+	hook.lock.Unlock()
+}
+
+func TestDispatcherPostsToEveryWebhook(t *testing.T) {
+	first, second := &webhook{}, &webhook{}
+	firstServer, secondServer := httptest.NewServer(first), httptest.NewServer(second)
+	defer firstServer.Close()
+	defer secondServer.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	dispatcher := NewDispatcher([]string{firstServer.URL, failing.URL, secondServer.URL}, time.Hour, time.Second)
+	assert.Nil(t, dispatcher.Start())
+	assert.True(t, dispatcher.Send(Alert{Kind: "arbitrage", Key: "a", Message: "buy low"}))
+	assert.Nil(t, dispatcher.Stop(context.Background()))
+
+	for _, hook := range []*webhook{first, second} {
+		assert.Len(t, hook.received, 1)
+		assert.Equal(t, "arbitrage", hook.received[0].Kind)
+		assert.Equal(t, "buy low", hook.received[0].Message)
+		assert.False(t, hook.received[0].Time.IsZero())
+	}
+}
+
+func TestDispatcherCooldown(t *testing.T) {
+	hook := &webhook{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	dispatcher := NewDispatcher([]string{server.URL}, time.Hour, time.Second)
+	assert.False(t, dispatcher.Send(Alert{Key: "a"}), "nothing is sent before it's started")
+	assert.Nil(t, dispatcher.Start())
+	now := time.Now()
+	assert.True(t, dispatcher.Send(Alert{Key: "a", Time: now}))
+	assert.False(t, dispatcher.Send(Alert{Key: "a", Time: now.Add(time.Minute)}))
+	assert.True(t, dispatcher.Send(Alert{Key: "b", Time: now.Add(time.Minute)}))
+	assert.True(t, dispatcher.Send(Alert{Key: "a", Time: now.Add(2 * time.Hour)}))
+	assert.Nil(t, dispatcher.Stop(context.Background()))
+	assert.Len(t, hook.received, 3)
+
+	assert.False(t, dispatcher.Send(Alert{Key: "c"}), "nothing is sent after it's stopped")
+}
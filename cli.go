@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// Exit codes let scripts tell a bad invocation apart from TradeOgre or Coinbase being down
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitUpstream = 3
+)
+
+var stdout io.Writer = os.Stdout
+var stderr io.Writer = os.Stderr
+var stdin io.Reader = os.Stdin
+
+// outputFlag adds the -o/--output flag every query command shares
+func outputFlag(flags *flag.FlagSet) *string {
+	output := flags.String("output", "table", "output format: table, json or csv")
+	flags.StringVar(output, "o", "table", "shorthand for -output")
+	return output
+}
+
+// parseInterspersed lets options come after the positional arguments, like convert 500 --fiat EUR
+func parseInterspersed(flags *flag.FlagSet, args []string) (positional []string, err error) {
+	for {
+		if err = flags.Parse(args); err != nil {
+			return positional, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseErrorCode treats -h as success and anything else the flag package rejects as bad input
+func parseErrorCode(err error) int {
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	return exitUsage
+}
+
+func validOutput(format string) bool {
+	switch strings.ToLower(format) {
+	case "table", "json", "csv":
+		return true
+	}
+	fmt.Fprintf(stderr, "Unknown output format %q, use table, json or csv\n", format)
+	return false
+}
+
+// validFiat checks a --fiat against the configured fiats, so a typo isn't mistaken for Coinbase being down
+func validFiat(fiat string) bool {
+	for _, configured := range lib.Fiats() {
+		if configured == strings.ToUpper(fiat) {
+			return true
+		}
+	}
+	fmt.Fprintf(stderr, "Unknown fiat %q, use one of %s\n", fiat, strings.Join(lib.Fiats(), ", "))
+	return false
+}
+
+func priceCommand(args []string) int {
+	flags := flag.NewFlagSet("price", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	fiat := flags.String("fiat", "USD", "fiat currency to price TRTL in")
+	force := flags.Bool("force", false, "skip any cached prices")
+	output := outputFlag(flags)
+	if _, err := parseInterspersed(flags, args); err != nil {
+		return parseErrorCode(err)
+	}
+	if !validOutput(*output) || !validFiat(*fiat) {
+		return exitUsage
+	}
+
+	price, err := lib.GetFiatPriceHash(*fiat, *force)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUpstream
+	}
+	return printPrices(*output, []lib.FiatPrice{price})
+}
+
+func convertCommand(args []string) int {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	fiat := flags.String("fiat", "USD", "fiat currency to convert into")
+	force := flags.Bool("force", false, "skip any cached prices")
+	net := flags.Bool("net", false, "take off the network, trade and withdrawal fees")
+	mode := flags.String("mode", lib.NetModeSpot, "with --net, sell at the spot price or walk the orderbook")
+	source := flags.String("source", "", "with --net, the BTC-TRTL source whose fees to use")
+	output := outputFlag(flags)
+	positional, parseErr := parseInterspersed(flags, args)
+	if parseErr != nil {
+		return parseErrorCode(parseErr)
+	}
+	if !validOutput(*output) || !validFiat(*fiat) {
+		return exitUsage
+	}
+	if len(positional) == 0 {
+		fmt.Fprintln(stderr, "Usage: turtle-utils convert <trtl> [--fiat EUR] [--net [--mode spot|orderbook] [--source name]] [--output table|json|csv]")
+		return exitUsage
+	}
+
+	var amounts []float64
+	for _, arg := range positional {
+		trtl, convErr := strconv.ParseFloat(arg, 64)
+		if convErr != nil || trtl < 0 {
+			fmt.Fprintf(stderr, "Problem converting %s to an amount of TRTL\n", arg)
+			return exitUsage
+		}
+		amounts = append(amounts, trtl)
+	}
+
+	if *net {
+		return convertNetCommand(amounts, strings.ToUpper(*fiat), *mode, *source, *force, *output)
+	}
+
+	price, err := lib.GetFiatPriceHash(*fiat, *force)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUpstream
+	}
+
+	var prices []lib.FiatPrice
+	for _, trtl := range amounts {
+		prices = append(prices, lib.ConvertTurtleFiatPrice(price, trtl))
+	}
+	return printPrices(*output, prices)
+}
+
+func convertNetCommand(amounts []float64, fiat string, mode string, source string, force bool, output string) int {
+	if mode != lib.NetModeSpot && mode != lib.NetModeOrderBook {
+		fmt.Fprintf(stderr, "--mode must be %s or %s\n", lib.NetModeSpot, lib.NetModeOrderBook)
+		return exitUsage
+	}
+	if _, sourceErr := lib.BtcSource(source); sourceErr != nil {
+		fmt.Fprintln(stderr, sourceErr)
+		return exitUsage
+	}
+
+	var proceeds []lib.NetProceeds
+	for _, trtl := range amounts {
+		net, netErr := lib.ConvertTurtleNet(trtl, fiat, mode, source, force)
+		if netErr != nil {
+			fmt.Fprintln(stderr, netErr)
+			return exitUpstream
+		}
+		proceeds = append(proceeds, net)
+	}
+
+	header := []string{"TRTL", "SOURCE", "FIAT", "GROSS", "FEES", "NET"}
+	var rows [][]string
+	for _, net := range proceeds {
+		rows = append(rows, []string{
+			strconv.FormatFloat(net.Trtl, 'f', -1, 64),
+			net.Source,
+			net.Fiat,
+			fmt.Sprintf("%.8f", net.Gross),
+			fmt.Sprintf("%.8f", net.TotalFees),
+			fmt.Sprintf("%.8f", net.Net),
+		})
+	}
+	return printOutput(output, header, rows, proceeds)
+}
+
+func historyCommand(args []string) int {
+	if len(args) > 0 && args[0] == "import" {
+		return historyImportCommand(args[1:])
+	}
+
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	since := flags.Duration("since", 24*time.Hour, "how far back to go")
+	output := outputFlag(flags)
+	if _, err := parseInterspersed(flags, args); err != nil {
+		return parseErrorCode(err)
+	}
+	if !validOutput(*output) {
+		return exitUsage
+	}
+
+	history, historyErr := lib.GetPriceHistory()
+	if historyErr != nil {
+		fmt.Fprintln(stderr, historyErr)
+		return exitError
+	}
+	if history.Path() == "" {
+		fmt.Fprintln(stderr, lib.ErrNoHistory)
+		return exitUsage
+	}
+
+	now := time.Now()
+	points := history.Range(now.Add(-*since), now)
+	return printHistory(*output, points)
+}
+
+// historyImportCommand backfills $HISTORY_FILE from a CSV in the format history -o csv writes, - reads stdin
+func historyImportCommand(args []string) int {
+	flags := flag.NewFlagSet("history import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: turtle-utils history import <file.csv|->")
+		fmt.Fprintln(stderr, "The CSV has a TIME column, a TRTL-BTC column and BTC-<fiat> columns, like history -o csv writes")
+	}
+	if err := flags.Parse(args); err != nil {
+		return parseErrorCode(err)
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	history, historyErr := lib.GetPriceHistory()
+	if historyErr != nil {
+		fmt.Fprintln(stderr, historyErr)
+		return exitError
+	}
+	if history.Path() == "" {
+		fmt.Fprintln(stderr, lib.ErrNoHistory)
+		return exitUsage
+	}
+
+	input := io.Reader(stdin)
+	if flags.Arg(0) != "-" {
+		file, openErr := os.Open(flags.Arg(0))
+		if openErr != nil {
+			fmt.Fprintln(stderr, openErr)
+			return exitUsage
+		}
+		defer file.Close()
+		input = file
+	}
+
+	points, parseErr := lib.ParseHistoryCSV(input)
+	if parseErr != nil {
+		fmt.Fprintln(stderr, parseErr)
+		return exitUsage
+	}
+	added, importErr := history.Import(points)
+	if importErr != nil {
+		fmt.Fprintln(stderr, importErr)
+		return exitError
+	}
+	fmt.Fprintf(stdout, "Imported %d of %d points into %s\n", added, len(points), history.Path())
+	return exitOK
+}
+
+// taxCommand values a ledger at its historical prices from $HISTORY_FILE and prints the gain or loss per year
+func taxCommand(args []string) int {
+	flags := flag.NewFlagSet("tax", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	fiat := flags.String("fiat", "USD", "currency to value transactions in")
+	method := flags.String("method", lib.MethodFIFO, "cost basis method: fifo, lifo or average")
+	policy := flags.String("policy", lib.PolicyInterpolate, "how to price between recorded points: interpolate or nearest")
+	tolerance := flags.Duration("tolerance", 24*time.Hour, "how far from a transaction a recorded price can be")
+	output := outputFlag(flags)
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return parseErrorCode(err)
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "Usage: turtle-utils tax <ledger.csv|-> [options]")
+		fmt.Fprintln(stderr, "The ledger has time, type (buy, sell, receive or send) and amount columns, and optionally value")
+		return exitUsage
+	}
+	if !validOutput(*output) {
+		return exitUsage
+	}
+
+	input := io.Reader(stdin)
+	if positional[0] != "-" {
+		file, openErr := os.Open(positional[0])
+		if openErr != nil {
+			fmt.Fprintln(stderr, openErr)
+			return exitUsage
+		}
+		defer file.Close()
+		input = file
+	}
+	transactions, parseErr := lib.ParseLedgerCSV(input)
+	if parseErr != nil {
+		fmt.Fprintln(stderr, parseErr)
+		return exitUsage
+	}
+
+	history, historyErr := lib.GetPriceHistory()
+	if historyErr != nil {
+		fmt.Fprintln(stderr, historyErr)
+		return exitError
+	}
+	currency := strings.ToUpper(*fiat)
+	if valueErr := history.ValueTransactions(transactions, currency, *policy, *tolerance); valueErr != nil {
+		fmt.Fprintln(stderr, valueErr)
+		return exitUsage
+	}
+	report, reportErr := lib.NewTaxReport(transactions, currency, strings.ToLower(*method))
+	if reportErr != nil {
+		fmt.Fprintln(stderr, reportErr)
+		return exitUsage
+	}
+
+	header := []string{"YEAR", "PROCEEDS", "COST BASIS", "GAIN"}
+	var rows [][]string
+	for _, year := range report.Years {
+		rows = append(rows, []string{
+			strconv.Itoa(year.Year),
+			fmt.Sprintf("%.2f", year.Proceeds),
+			fmt.Sprintf("%.2f", year.CostBasis),
+			fmt.Sprintf("%.2f", year.Gain),
+		})
+	}
+	return printOutput(*output, header, rows, report)
+}
+
+func printPrices(format string, prices []lib.FiatPrice) int {
+	header := []string{"TRTL", "BTC", "FIAT", "VALUE"}
+	var rows [][]string
+	for _, price := range prices {
+		rows = append(rows, []string{
+			strconv.FormatFloat(price.Trtl, 'f', -1, 64),
+			fmt.Sprintf("%.8f", price.Btc),
+			price.Fiat,
+			fmt.Sprintf("%.8f", price.Value),
+		})
+	}
+	return printOutput(format, header, rows, prices)
+}
+
+func printHistory(format string, points []lib.PricePoint) int {
+	var fiats []string
+	seen := map[string]bool{}
+	for _, point := range points {
+		for fiat := range point.BtcToFiat {
+			if !seen[fiat] {
+				seen[fiat] = true
+				fiats = append(fiats, fiat)
+			}
+		}
+	}
+	sort.Strings(fiats)
+
+	header := []string{"TIME", "TRTL-BTC"}
+	for _, fiat := range fiats {
+		header = append(header, "BTC-"+fiat)
+	}
+	var rows [][]string
+	for _, point := range points {
+		row := []string{point.Time.Format(time.RFC3339), ""}
+		if point.TrtlToBtc > 0 {
+			row[1] = strconv.FormatFloat(point.TrtlToBtc, 'f', -1, 64)
+		}
+		for _, fiat := range fiats {
+			value, ok := point.BtcToFiat[fiat]
+			if ok {
+				row = append(row, strconv.FormatFloat(value, 'f', -1, 64))
+			} else {
+				row = append(row, "")
+			}
+		}
+		rows = append(rows, row)
+	}
+	if points == nil {
+		points = []lib.PricePoint{}
+	}
+	return printOutput(format, header, rows, points)
+}
+
+// printOutput writes rows as an aligned table or CSV, or value as JSON
+func printOutput(format string, header []string, rows [][]string, value interface{}) int {
+	switch strings.ToLower(format) {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+	case "csv":
+		writer := csv.NewWriter(stdout)
+		writer.Write(header)
+		writer.WriteAll(rows)
+		if err := writer.Error(); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+	case "table":
+		writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		writer.Flush()
+	default:
+		validOutput(format)
+		return exitUsage
+	}
+	return exitOK
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y4htse/turtle-utils/lib"
+)
+
+func TestHistoryExportImportRoundTrip(t *testing.T) {
+	points := []lib.PricePoint{
+		{Time: time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC), TrtlToBtc: 1.234567891e-9, BtcToFiat: map[string]float64{"USD": 10987.654321}},
+		{Time: time.Date(2018, 3, 2, 0, 0, 0, 0, time.UTC), TrtlToBtc: 3e-8, BtcToFiat: map[string]float64{"EUR": 8765.4321}},
+	}
+
+	var out bytes.Buffer
+	defer func(previous io.Writer) { stdout = previous }(stdout)
+	stdout = &out
+	assert.Equal(t, exitOK, printHistory("csv", points))
+
+	imported, err := lib.ParseHistoryCSV(&out)
+	assert.Nil(t, err)
+	if assert.Len(t, imported, 2) {
+		for i := range points {
+			assert.True(t, points[i].Time.Equal(imported[i].Time))
+			assert.Equal(t, points[i].TrtlToBtc, imported[i].TrtlToBtc)
+			assert.Equal(t, points[i].BtcToFiat, imported[i].BtcToFiat)
+		}
+	}
+}
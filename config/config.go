@@ -0,0 +1,518 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	lib "github.com/y4htse/turtle-utils/lib"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config is everything the service can be configured with
+// Values are layered: defaults, then the YAML file, then the environment, then command line flags
+type Config struct {
+	Server     Server    `yaml:"server"`
+	Sources    []Source  `yaml:"sources"`
+	FiatURL    string    `yaml:"fiatUrl"`
+	Fiats      []string  `yaml:"fiats"`
+	DaemonURL  string    `yaml:"daemonUrl"`
+	NetworkFee float64   `yaml:"networkFee"`
+	Cache      Cache     `yaml:"cache"`
+	Timeouts   Timeouts  `yaml:"timeouts"`
+	RateLimit  RateLimit `yaml:"rateLimit"`
+	Batch      Batch     `yaml:"batch"`
+	Features   Features  `yaml:"features"`
+	Poller     Poller    `yaml:"poller"`
+	Arbitrage  Arbitrage `yaml:"arbitrage"`
+	Alerts     Alerts    `yaml:"alerts"`
+	Anomaly    Anomaly   `yaml:"anomaly"`
+	History    History   `yaml:"history"`
+	Admin      Admin     `yaml:"admin"`
+	Log        Log       `yaml:"log"`
+}
+
+// Server is where and how the web server listens
+// Socket listens on a unix socket instead of address:port, TLSCert and TLSKey turn on HTTPS
+// Assets is a directory to read templates and static files from instead of the ones built in, for live editing
+type Server struct {
+	Address         string        `yaml:"address"`
+	Port            string        `yaml:"port"`
+	Socket          string        `yaml:"socket"`
+	TLSCert         string        `yaml:"tlsCert"`
+	TLSKey          string        `yaml:"tlsKey"`
+	Assets          string        `yaml:"assets"`
+	ReadTimeout     time.Duration `yaml:"readTimeout"`
+	WriteTimeout    time.Duration `yaml:"writeTimeout"`
+	IdleTimeout     time.Duration `yaml:"idleTimeout"`
+	RequestTimeout  time.Duration `yaml:"requestTimeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+// Poller pulls and records the price in the background, zero turns it off
+type Poller struct {
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Arbitrage is the net spread between exchanges, as a percent, that counts as an opportunity
+// Interval checks for them in the background and alerts the webhooks, zero turns it off
+type Arbitrage struct {
+	Threshold float64       `yaml:"threshold"`
+	Interval  time.Duration `yaml:"interval"`
+}
+
+// Alerts are webhooks that get a JSON POST for each alert, the same alert is sent at most once per cooldown
+type Alerts struct {
+	Webhooks []string      `yaml:"webhooks"`
+	Cooldown time.Duration `yaml:"cooldown"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// Anomaly is when a new quote looks wrong enough to quarantine instead of publishing it, zero turns a check off
+// Jumps and z-scores are against the prices recorded in the last window, once there are minSamples of them
+// Divergence is from the median of every source's quote and needs three sources
+// A source quarantined confirmations times in a row for the same price, over at least confirmFor, is believed, it's a
+// real move. The time matters since a fat-finger print stays the last price until the next trade
+type Anomaly struct {
+	Window            time.Duration `yaml:"window"`
+	MinSamples        int           `yaml:"minSamples"`
+	ZScore            float64       `yaml:"zScore"`
+	JumpPercent       float64       `yaml:"jumpPercent"`
+	DivergencePercent float64       `yaml:"divergencePercent"`
+	Confirmations     int           `yaml:"confirmations"`
+	ConfirmFor        time.Duration `yaml:"confirmFor"`
+}
+
+// History is how long recorded prices are kept in memory, zero keeps them all
+// Older prices stay in $HISTORY_FILE but can't be converted at or charted until retention is raised
+type History struct {
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Admin protects the admin endpoints with a bearer token, they're turned off when it isn't set
+type Admin struct {
+	Token string `yaml:"token"`
+}
+
+// Log is how much is logged and whether it is written as JSON or text
+type Log struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Source is an exchange market TRTL prices are pulled from, weighted against the other sources for the same pair
+type Source struct {
+	Name   string  `yaml:"name"`
+	Kind   string  `yaml:"kind"`
+	URL    string  `yaml:"url"`
+	Pair   string  `yaml:"pair"`
+	Weight float64 `yaml:"weight"`
+	Fees   Fees    `yaml:"fees"`
+}
+
+// Fees is what a source charges, Trade is a percent of each trade and Withdrawal is in the market currency
+type Fees struct {
+	Trade      float64 `yaml:"trade"`
+	Withdrawal float64 `yaml:"withdrawal"`
+}
+
+// Cache is how long pulled prices are reused before going upstream again
+type Cache struct {
+	TrtlPrice time.Duration `yaml:"trtlPrice"`
+	FiatPrice time.Duration `yaml:"fiatPrice"`
+}
+
+// Timeouts are the HTTP client timeouts for each upstream
+type Timeouts struct {
+	Exchange time.Duration `yaml:"exchange"`
+	Fiat     time.Duration `yaml:"fiat"`
+	Daemon   time.Duration `yaml:"daemon"`
+}
+
+// RateLimit is how many requests a client IP can make, zero turns it off
+type RateLimit struct {
+	RequestsPerMinute int `yaml:"requestsPerMinute"`
+	Burst             int `yaml:"burst"`
+}
+
+// Batch limits the size of batch conversion requests
+type Batch struct {
+	MaxItems int   `yaml:"maxItems"`
+	MaxBytes int64 `yaml:"maxBytes"`
+}
+
+// Features turn optional endpoints on and off
+type Features struct {
+	Quotes     bool `yaml:"quotes"`
+	Oracle     bool `yaml:"oracle"`
+	OrderBook  bool `yaml:"orderBook"`
+	Market     bool `yaml:"market"`
+	Supply     bool `yaml:"supply"`
+	Emission   bool `yaml:"emission"`
+	Mining     bool `yaml:"mining"`
+	Charts     bool `yaml:"charts"`
+	Badges     bool `yaml:"badges"`
+	Batch      bool `yaml:"batch"`
+	Tax        bool `yaml:"tax"`
+	Routes     bool `yaml:"routes"`
+	Fees       bool `yaml:"fees"`
+	Markets    bool `yaml:"markets"`
+	Indicators bool `yaml:"indicators"`
+}
+
+// Enabled looks a feature up by its YAML name
+func (features Features) Enabled(name string) bool {
+	switch name {
+	case "quotes":
+		return features.Quotes
+	case "oracle":
+		return features.Oracle
+	case "orderBook":
+		return features.OrderBook
+	case "market":
+		return features.Market
+	case "supply":
+		return features.Supply
+	case "emission":
+		return features.Emission
+	case "mining":
+		return features.Mining
+	case "charts":
+		return features.Charts
+	case "badges":
+		return features.Badges
+	case "batch":
+		return features.Batch
+	case "tax":
+		return features.Tax
+	case "routes":
+		return features.Routes
+	case "fees":
+		return features.Fees
+	case "markets":
+		return features.Markets
+	case "indicators":
+		return features.Indicators
+	}
+	return false
+}
+
+// Default is the config used when nothing else is set
+func Default() Config {
+	return Config{
+		Server: Server{
+			Address:         "0.0.0.0",
+			Port:            "8675",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			RequestTimeout:  8 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Sources: []Source{
+			{Name: "tradeogre", Kind: "tradeogre", URL: "https://tradeogre.com/api/v1", Pair: "BTC-TRTL", Weight: 1},
+		},
+		FiatURL:    "https://api.coinbase.com/v2",
+		Fiats:      []string{"USD"},
+		NetworkFee: 0.1,
+		Cache: Cache{
+			TrtlPrice: 30 * time.Second,
+			FiatPrice: 60 * time.Second,
+		},
+		Timeouts: Timeouts{
+			Exchange: 3 * time.Second,
+			Fiat:     2 * time.Second,
+			Daemon:   3 * time.Second,
+		},
+		Batch: Batch{
+			MaxItems: 10000,
+			MaxBytes: 1 << 20,
+		},
+		Features: Features{
+			Quotes:     true,
+			Oracle:     true,
+			OrderBook:  true,
+			Market:     true,
+			Supply:     true,
+			Emission:   true,
+			Mining:     true,
+			Charts:     true,
+			Badges:     true,
+			Batch:      true,
+			Tax:        true,
+			Routes:     true,
+			Fees:       true,
+			Markets:    true,
+			Indicators: true,
+		},
+		Arbitrage: Arbitrage{
+			Threshold: 1,
+		},
+		Alerts: Alerts{
+			Cooldown: time.Hour,
+			Timeout:  5 * time.Second,
+		},
+		Anomaly: Anomaly{
+			Window:            6 * time.Hour,
+			MinSamples:        10,
+			JumpPercent:       50,
+			DivergencePercent: 25,
+			Confirmations:     3,
+			ConfirmFor:        15 * time.Minute,
+		},
+		History: History{
+			Retention: 0,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load builds a config from the defaults, the YAML file at path (skipped when empty) and the environment
+func Load(path string) (config Config, err error) {
+	config = Default()
+
+	if path != "" {
+		contents, readErr := ioutil.ReadFile(path)
+		if readErr != nil {
+			return config, errors.Wrap(readErr, "Problem reading the config file")
+		}
+		if yamlErr := yaml.UnmarshalStrict(contents, &config); yamlErr != nil {
+			return config, errors.Wrapf(yamlErr, "Problem parsing %s", path)
+		}
+	}
+
+	config.applyEnv()
+	return config, nil
+}
+
+// applyEnv overrides the config with the environment variables the service has always used
+func (config *Config) applyEnv() {
+	if port := os.Getenv("PORT"); port != "" {
+		config.Server.Port = port
+	}
+	if address := os.Getenv("ADDRESS"); address != "" {
+		config.Server.Address = address
+	}
+	if fiats := os.Getenv("FIATS"); fiats != "" {
+		config.Fiats = strings.Split(fiats, ",")
+	}
+	if daemonURL := os.Getenv("DAEMON_URL"); daemonURL != "" {
+		config.DaemonURL = daemonURL
+	}
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		config.Log.Level = level
+	}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		config.Log.Format = format
+	}
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		config.Admin.Token = token
+	}
+}
+
+// Validate normalizes the config and reports everything wrong with it at once
+func (config *Config) Validate() error {
+	var problems []string
+
+	if config.Server.Port == "" && config.Server.Socket == "" {
+		problems = append(problems, "server.port or server.socket must be set")
+	}
+	if (config.Server.TLSCert == "") != (config.Server.TLSKey == "") {
+		problems = append(problems, "server.tlsCert and server.tlsKey must be set together")
+	}
+	if config.Server.ReadTimeout < 0 || config.Server.WriteTimeout < 0 || config.Server.IdleTimeout < 0 || config.Server.RequestTimeout < 0 || config.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server timeouts can't be negative and shutdownTimeout must be more than 0")
+	}
+	if config.Server.Assets != "" {
+		if info, statErr := os.Stat(config.Server.Assets); statErr != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("server.assets %q is not a directory", config.Server.Assets))
+		}
+	}
+	if config.Poller.Interval < 0 {
+		problems = append(problems, "poller.interval can't be negative")
+	}
+	if config.Arbitrage.Threshold < 0 || config.Arbitrage.Interval < 0 {
+		problems = append(problems, "arbitrage.threshold and arbitrage.interval can't be negative")
+	}
+	if config.Arbitrage.Interval > 0 && len(config.Alerts.Webhooks) == 0 {
+		problems = append(problems, "arbitrage.interval needs alerts.webhooks to send to")
+	}
+	for i, webhook := range config.Alerts.Webhooks {
+		if parsed, urlErr := url.ParseRequestURI(webhook); urlErr != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			problems = append(problems, fmt.Sprintf("alerts.webhooks[%d] is not an http or https URL", i))
+		}
+	}
+	if config.Alerts.Cooldown < 0 || config.Alerts.Timeout <= 0 {
+		problems = append(problems, "alerts.cooldown can't be negative and alerts.timeout must be more than 0")
+	}
+	anomaly := config.Anomaly
+	if anomaly.Window < 0 || anomaly.MinSamples < 0 || anomaly.ZScore < 0 || anomaly.JumpPercent < 0 || anomaly.DivergencePercent < 0 || anomaly.Confirmations < 0 || anomaly.ConfirmFor < 0 {
+		problems = append(problems, "anomaly thresholds can't be negative")
+	}
+	if config.History.Retention < 0 {
+		problems = append(problems, "history.retention can't be negative")
+	}
+	if config.Admin.Token != "" && len(config.Admin.Token) < 16 {
+		problems = append(problems, "admin.token must be at least 16 characters")
+	}
+
+	if len(config.Sources) == 0 {
+		problems = append(problems, "at least one source must be configured")
+	}
+	names := map[string]bool{}
+	for i := range config.Sources {
+		source := &config.Sources[i]
+		source.Pair = strings.ToUpper(source.Pair)
+		source.URL = strings.TrimRight(source.URL, "/")
+		if source.Name == "" {
+			problems = append(problems, fmt.Sprintf("sources[%d].name must be set", i))
+		}
+		if names[source.Name+source.Pair] {
+			problems = append(problems, fmt.Sprintf("sources[%d] %s %s is listed twice", i, source.Name, source.Pair))
+		}
+		names[source.Name+source.Pair] = true
+		if source.Kind != "tradeogre" && source.Kind != "coinbase" {
+			problems = append(problems, fmt.Sprintf("sources[%d].kind %q is not supported, use tradeogre or coinbase", i, source.Kind))
+		}
+		if _, urlErr := url.ParseRequestURI(source.URL); urlErr != nil {
+			problems = append(problems, fmt.Sprintf("sources[%d].url %q is not a URL", i, source.URL))
+		}
+		if len(strings.Split(source.Pair, "-")) != 2 {
+			problems = append(problems, fmt.Sprintf("sources[%d].pair %q must look like BTC-TRTL", i, source.Pair))
+		}
+		if source.Weight <= 0 {
+			problems = append(problems, fmt.Sprintf("sources[%d].weight must be more than 0", i))
+		}
+		if source.Fees.Trade < 0 || source.Fees.Trade >= 100 || source.Fees.Withdrawal < 0 {
+			problems = append(problems, fmt.Sprintf("sources[%d].fees.trade must be a percent from 0 to 100 and fees.withdrawal can't be negative", i))
+		}
+	}
+
+	if config.NetworkFee < 0 {
+		problems = append(problems, "networkFee can't be negative")
+	}
+
+	config.FiatURL = strings.TrimRight(config.FiatURL, "/")
+	if _, urlErr := url.ParseRequestURI(config.FiatURL); urlErr != nil {
+		problems = append(problems, fmt.Sprintf("fiatUrl %q is not a URL", config.FiatURL))
+	}
+
+	var fiats []string
+	for _, fiat := range config.Fiats {
+		fiat = strings.ToUpper(strings.TrimSpace(fiat))
+		if fiat != "" {
+			fiats = append(fiats, fiat)
+		}
+	}
+	config.Fiats = fiats
+	if len(config.Fiats) == 0 {
+		problems = append(problems, "at least one fiat must be configured")
+	}
+
+	if config.DaemonURL != "" {
+		if _, urlErr := url.ParseRequestURI(config.DaemonURL); urlErr != nil {
+			problems = append(problems, fmt.Sprintf("daemonUrl %q is not a URL", config.DaemonURL))
+		}
+	}
+
+	if config.Cache.TrtlPrice < 0 || config.Cache.FiatPrice < 0 {
+		problems = append(problems, "cache TTLs can't be negative")
+	}
+	if config.Timeouts.Exchange <= 0 || config.Timeouts.Fiat <= 0 || config.Timeouts.Daemon <= 0 {
+		problems = append(problems, "timeouts must be more than 0")
+	}
+	if config.RateLimit.RequestsPerMinute < 0 || config.RateLimit.Burst < 0 {
+		problems = append(problems, "rate limits can't be negative")
+	}
+	if config.RateLimit.RequestsPerMinute > 0 && config.RateLimit.Burst == 0 {
+		config.RateLimit.Burst = config.RateLimit.RequestsPerMinute
+	}
+
+	config.Log.Level = strings.ToLower(config.Log.Level)
+	config.Log.Format = strings.ToLower(config.Log.Format)
+	switch config.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("log.level %q must be debug, info, warn or error", config.Log.Level))
+	}
+	if config.Log.Format != "json" && config.Log.Format != "text" {
+		problems = append(problems, fmt.Sprintf("log.format %q must be json or text", config.Log.Format))
+	}
+
+	if config.Batch.MaxItems <= 0 || config.Batch.MaxBytes <= 0 {
+		problems = append(problems, "batch.maxItems and batch.maxBytes must be more than 0")
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// LibSettings turns the config into the settings lib runs with
+func (config Config) LibSettings() lib.Settings {
+	settings := lib.Settings{
+		FiatURL:         config.FiatURL,
+		Fiats:           config.Fiats,
+		DaemonURL:       config.DaemonURL,
+		NetworkFee:      config.NetworkFee,
+		TrtlCacheTTL:    config.Cache.TrtlPrice,
+		FiatCacheTTL:    config.Cache.FiatPrice,
+		ExchangeTimeout: config.Timeouts.Exchange,
+		FiatTimeout:     config.Timeouts.Fiat,
+		DaemonTimeout:   config.Timeouts.Daemon,
+		Anomaly: lib.AnomalySettings{
+			Window:            config.Anomaly.Window,
+			MinSamples:        config.Anomaly.MinSamples,
+			ZScore:            config.Anomaly.ZScore,
+			JumpPercent:       config.Anomaly.JumpPercent,
+			DivergencePercent: config.Anomaly.DivergencePercent,
+			Confirmations:     config.Anomaly.Confirmations,
+			ConfirmFor:        config.Anomaly.ConfirmFor,
+		},
+		HistoryRetention: config.History.Retention,
+	}
+	for _, source := range config.Sources {
+		settings.Sources = append(settings.Sources, lib.Source{
+			Name:   source.Name,
+			Kind:   source.Kind,
+			URL:    source.URL,
+			Pair:   source.Pair,
+			Weight: source.Weight,
+			Fees: lib.FeeSchedule{
+				TradePercent: source.Fees.Trade,
+				Withdrawal:   source.Fees.Withdrawal,
+			},
+		})
+	}
+	return settings
+}
+
+var (
+	currentLock sync.RWMutex
+	current     = Default()
+)
+
+// Apply makes config the current config and hands its settings and logging to lib
+func Apply(config Config) {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+	current = config
+	lib.Configure(config.LibSettings())
+	lib.SetupLogging(os.Stderr, config.Log.Level, config.Log.Format)
+}
+
+// Current is the config the service is running with
+func Current() Config {
+	currentLock.RLock()
+	defer currentLock.RUnlock()
+	return current
+}
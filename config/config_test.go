@@ -0,0 +1,88 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, contents string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadLayersFileAndEnv(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+server:
+  port: "9000"
+fiats: [eur, " gbp "]
+cache:
+  trtlPrice: 5s
+features:
+  mining: false
+`)
+	defer cleanup()
+	os.Setenv("PORT", "9100")
+	defer os.Unsetenv("PORT")
+
+	loaded, err := Load(path)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded.Validate())
+	assert.Equal(t, "9100", loaded.Server.Port)
+	assert.Equal(t, "0.0.0.0", loaded.Server.Address)
+	assert.Equal(t, []string{"EUR", "GBP"}, loaded.Fiats)
+	assert.Equal(t, 5*time.Second, loaded.Cache.TrtlPrice)
+	assert.Equal(t, 60*time.Second, loaded.Cache.FiatPrice)
+	assert.False(t, loaded.Features.Enabled("mining"))
+	assert.True(t, loaded.Features.Enabled("quotes"))
+	assert.Equal(t, "https://tradeogre.com/api/v1", loaded.LibSettings().Sources[0].URL)
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path, cleanup := writeConfig(t, "sauces: []\n")
+	defer cleanup()
+
+	_, err := Load(path)
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	config := Default()
+	config.Sources = append(config.Sources, Source{Name: "other", Kind: "bittrex", URL: "nope", Pair: "TRTL", Weight: 0})
+	config.Timeouts.Fiat = 0
+	config.RateLimit.RequestsPerMinute = 60
+
+	err := config.Validate()
+	assert.NotNil(t, err)
+	for _, problem := range []string{"kind \"bittrex\"", "url \"nope\"", "pair \"TRTL\"", "weight", "timeouts"} {
+		assert.Contains(t, err.Error(), problem)
+	}
+	assert.Equal(t, 60, config.RateLimit.Burst)
+}
+
+func TestValidateAnomalyAndAdmin(t *testing.T) {
+	config := Default()
+	config.Anomaly.JumpPercent = -1
+	config.Admin.Token = "short"
+
+	err := config.Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "anomaly thresholds")
+	assert.Contains(t, err.Error(), "admin.token")
+
+	config = Default()
+	config.Admin.Token = "0123456789abcdef"
+	assert.Nil(t, config.Validate())
+	assert.Equal(t, 25.0, config.LibSettings().Anomaly.DivergencePercent)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// QuarantineHandler lists the quotes that looked wrong and weren't published, newest first
+func QuarantineHandler(c *gin.Context) {
+	quarantined := lib.Quarantine()
+	var rows [][]Field
+	for _, quote := range quarantined {
+		rows = append(rows, []Field{
+			{Name: "time", Value: quote.Time.Format(time.RFC3339)},
+			{Name: "source", Value: quote.Source},
+			{Name: "pair", Value: quote.Pair},
+			{Name: "price", Value: Float(quote.Price)},
+			{Name: "reason", Value: quote.Reason},
+			{Name: "reference", Value: Float(quote.Reference)},
+		})
+	}
+	Render(c, 200, Response{
+		Name: "quarantine",
+		JSON: gin.H{
+			"quarantined": quarantined,
+		},
+		Rows: rows,
+		Text: Float(float64(len(quarantined))),
+	})
+}
+
+// ClearQuarantineHandler forgets the quarantined quotes once they've been looked at
+func ClearQuarantineHandler(c *gin.Context) {
+	lib.ClearQuarantine()
+	c.Status(204)
+}
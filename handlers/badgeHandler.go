@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+	svg "github.com/y4htse/turtle-utils/svg"
+)
+
+// currencySymbols are written before an amount, other currencies get their code after it
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"BTC": "Ƀ",
+}
+
+// badgeFlatPercent is how far, as a percent, the price has to move in 24 hours to turn the badge green or red
+const badgeFlatPercent = 0.5
+
+// BadgeHandler draws a shields.io style badge with the value of ?amount= TRTL in ?fiat= and its 24h change
+// When the price can't be pulled the last recorded one is shown in grey and marked stale, as is the last accepted price
+// when every fresh quote was quarantined
+func BadgeHandler(c *gin.Context) {
+	currency := strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	style := c.DefaultQuery("style", "flat")
+	amount, amountErr := strconv.ParseFloat(c.DefaultQuery("amount", "1"), 64)
+	if amountErr != nil || amount <= 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		c.String(400, "amount must be a number more than 0")
+		return
+	}
+	if currencies := append([]string{"BTC"}, lib.Fiats()...); !contains(currencies, currency) {
+		c.String(400, "fiat must be one of %s", strings.Join(currencies, ", "))
+		return
+	}
+	if !contains(svg.BadgeStyles, style) {
+		c.String(400, "style must be one of %s", strings.Join(svg.BadgeStyles, ", "))
+		return
+	}
+
+	label := "TRTL"
+	if amount != 1 {
+		label = badgeNumber(amount) + " TRTL"
+	}
+
+	history, historyErr := lib.GetPriceHistory()
+	price, fetchedAt, stale, priceErr := lib.GetTrtlPriceFetchedContext(c.Request.Context(), currency, false)
+	if priceErr != nil {
+		lib.Logger(c.Request.Context()).Warn("Problem getting the badge price", "error", priceErr.Error())
+		c.Header("Cache-Control", "public, max-age=10")
+		last, ok := lib.PriceSample{}, false
+		if historyErr == nil {
+			last, ok = history.Last(currency)
+		}
+		if !ok {
+			c.Data(200, "image/svg+xml", svg.Badge(label, "unavailable", svg.LightGrey, style))
+			return
+		}
+		price, fetchedAt, stale = last.Price, last.Time, true
+	}
+	if stale {
+		c.Header("Cache-Control", "public, max-age=10")
+		message := fmt.Sprintf("%s (stale %s)", currencyAmount(currency, amount*price), staleFor(time.Since(fetchedAt)))
+		c.Data(200, "image/svg+xml", svg.Badge(label, message, svg.LightGrey, style))
+		return
+	}
+
+	message, color := currencyAmount(currency, amount*price), svg.Blue
+	if historyErr == nil {
+		if change, ok := history.Change(currency, price, time.Now(), 24*time.Hour); ok {
+			switch {
+			case change >= badgeFlatPercent:
+				message, color = fmt.Sprintf("%s ▲%s%%", message, badgeNumber(math.Abs(change))), svg.Green
+			case change <= -badgeFlatPercent:
+				message, color = fmt.Sprintf("%s ▼%s%%", message, badgeNumber(math.Abs(change))), svg.Red
+			}
+		}
+	}
+	c.Header("Cache-Control", "public, max-age=60")
+	c.Data(200, "image/svg+xml", svg.Badge(label, message, color, style))
+}
+
+// badgeNumber keeps 2 significant figures for small numbers and no more than 2 decimals for big ones
+func badgeNumber(value float64) string {
+	if value >= 1 || value == 0 {
+		return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+	}
+	decimals := int(math.Ceil(-math.Log10(value))) + 1
+	return strings.TrimRight(strings.TrimRight(strconv.FormatFloat(value, 'f', decimals, 64), "0"), ".")
+}
+
+func currencyAmount(currency string, value float64) string {
+	if symbol, ok := currencySymbols[currency]; ok {
+		return symbol + badgeNumber(value)
+	}
+	return badgeNumber(value) + " " + currency
+}
+
+// staleFor is how old a price is, in the biggest whole unit
+func staleFor(age time.Duration) string {
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(age.Minutes()))
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBadgeNumber(t *testing.T) {
+	assert.Equal(t, "0.00004", badgeNumber(0.00004))
+	assert.Equal(t, "0.000041", badgeNumber(0.0000412))
+	assert.Equal(t, "3.46", badgeNumber(3.456))
+	assert.Equal(t, "$0.04", currencyAmount("USD", 0.04))
+	assert.Equal(t, "12 CAD", currencyAmount("CAD", 12))
+}
@@ -1,38 +1,131 @@
 package handlers
 
 import (
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	lib "github.com/y4htse/turtle-utils/lib"
 )
 
-// BaseHandler is the health check function
+// BaseHandler is the converter page
+// It converts ?amount= TRTL into ?currency= (or back with from=currency) and renders the page, or with
+// ?format=json just the numbers, which is what the page polls to keep itself up to date
 func BaseHandler(c *gin.Context) {
-	trtl := c.DefaultQuery("trtl", "1")
+	amount := c.DefaultQuery("amount", c.DefaultQuery("trtl", "1"))
+	currency := strings.ToUpper(c.DefaultQuery("currency", "USD"))
+	from := c.DefaultQuery("from", "trtl")
 
-	trtlInt, intConvErr := strconv.ParseInt(trtl, 10, 64)
+	amountFloat, parseErr := strconv.ParseFloat(amount, 64)
+	if parseErr != nil || amountFloat < 0 || math.IsInf(amountFloat, 0) || math.IsNaN(amountFloat) {
+		converterError(c, 400, fmt.Errorf("%s is not an amount", amount))
+		return
+	}
+	if from != "trtl" && from != "currency" {
+		converterError(c, 400, fmt.Errorf("from must be trtl or currency, not %s", from))
+		return
+	}
+	currencies := append([]string{"BTC"}, lib.Fiats()...)
+	if !contains(currencies, currency) {
+		converterError(c, 400, fmt.Errorf("currency must be one of %s", strings.Join(currencies, ", ")))
+		return
+	}
 
-	if intConvErr != nil {
-		errHandler(intConvErr, c)
+	price, fetchedAt, _, priceErr := lib.GetTrtlPriceFetchedContext(c.Request.Context(), currency, false)
+	if priceErr == nil && price <= 0 {
+		priceErr = fmt.Errorf("TRTL has no price in %s right now", currency)
+	}
+	if priceErr != nil {
+		converterError(c, 500, priceErr)
 		return
 	}
-	price, err := lib.ConvertTurtle(trtlInt, false)
-	if err != nil {
-		errHandler(err, c)
+
+	trtl, value := amountFloat, rounded(amountFloat*price)
+	if from == "currency" {
+		trtl, value = rounded(amountFloat/price), amountFloat
+	}
+
+	// updated is when the price was pulled, not now, so the page can tell a cached price is getting old
+	updated := fetchedAt.UTC()
+	var change interface{}
+	if history, historyErr := lib.GetPriceHistory(); historyErr == nil {
+		if percent, ok := history.Change(currency, price, updated, 24*time.Hour); ok {
+			change = percent
+		}
+	}
+	price = rounded(price)
+
+	if c.Query("format") != "" {
+		Render(c, 200, Response{
+			Name: "conversion",
+			JSON: gin.H{
+				"amount":    amountFloat,
+				"from":      from,
+				"currency":  currency,
+				"trtl":      trtl,
+				"value":     value,
+				"price":     price,
+				"change24h": change,
+				"updated":   updated.Format(time.RFC3339),
+			},
+			Fields: []Field{
+				{Name: "trtl", Value: Float(trtl)},
+				{Name: "currency", Value: currency},
+				{Name: "value", Value: Float(value)},
+				{Name: "price", Value: Float(price)},
+				{Name: "updated", Value: updated.Format(time.RFC3339)},
+			},
+			Text: Float(value),
+		})
 		return
 	}
+
+	changeText := "no history yet"
+	if percent, ok := change.(float64); ok {
+		changeText = fmt.Sprintf("%+.2f%%", percent)
+	}
 	c.HTML(http.StatusOK, "index.tmpl", gin.H{
-		"trtl":     trtlInt,
-		"usdPrice": price.CurrentUsdPrice,
-		"btcPrice": price.CurrentBtcPrice,
+		"amount":     amount,
+		"from":       from,
+		"currency":   currency,
+		"currencies": currencies,
+		"trtl":       Float(trtl),
+		"value":      Float(value),
+		"price":      Float(price),
+		"change":     changeText,
+		"updated":    updated.Format(time.RFC3339),
 	})
+}
 
+// converterError answers the page's polling in JSON and everyone else with the error page
+func converterError(c *gin.Context, status int, err error) {
+	if c.Query("format") != "" {
+		RenderError(c, status, err.Error())
+		return
+	}
+	errHandler(status, err, c)
+}
+
+// rounded cuts a number to 8 decimal places, a satoshi for BTC
+func rounded(value float64) float64 {
+	return math.Round(value*1e8) / 1e8
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
 }
 
-func errHandler(err error, c *gin.Context) {
-	c.HTML(http.StatusInternalServerError, "500.tmpl", gin.H{
+func errHandler(status int, err error, c *gin.Context) {
+	c.HTML(status, "500.tmpl", gin.H{
 		"error": err,
 	})
 }
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	config "github.com/y4htse/turtle-utils/config"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// BatchConvertHandler converts a JSON array or CSV of amounts against one price snapshot
+// The CSV can be the request body (Content-Type: text/csv) or a multipart upload called file
+// Items that can't be read get an error in their result without failing the rest of the batch
+func BatchConvertHandler(c *gin.Context) {
+	fiat := strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	forcedBool, parseBoolErr := strconv.ParseBool(strings.ToUpper(c.DefaultQuery("force", "false")))
+	if parseBoolErr != nil {
+		forcedBool = false
+	}
+	if !contains(lib.Fiats(), fiat) {
+		RenderError(c, 400, fmt.Sprintf("fiat must be one of %s", strings.Join(lib.Fiats(), ", ")))
+		return
+	}
+
+	limits := config.Current().Batch
+	body, ok := readUpload(c, limits.MaxBytes)
+	if !ok {
+		return
+	}
+
+	items, parseErr := parseBatch(c, body, limits.MaxItems)
+	if parseErr == lib.ErrTooManyItems {
+		RenderError(c, 413, fmt.Sprintf("batch can't have more than %d items", limits.MaxItems))
+		return
+	}
+	if parseErr != nil {
+		RenderError(c, 400, parseErr.Error())
+		return
+	}
+
+	price, priceErr := lib.GetFiatPriceHashContext(c.Request.Context(), fiat, forcedBool)
+	if priceErr != nil {
+		RenderError(c, 500, "Problem getting the price of turtle")
+		return
+	}
+
+	results := lib.ConvertBatch(price, items)
+	failed := 0
+	var rows [][]Field
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+		row := []Field{
+			{Name: "index", Value: strconv.Itoa(result.Index)},
+			{Name: "label", Value: result.Label},
+			{Name: "amount", Value: Float(result.Amount)},
+			{Name: "btc", Value: ""},
+			{Name: "value", Value: ""},
+			{Name: "error", Value: result.Error},
+		}
+		if result.Btc != nil {
+			row[3].Value = Float(*result.Btc)
+		}
+		if result.Value != nil {
+			row[4].Value = Float(*result.Value)
+		}
+		rows = append(rows, row)
+	}
+	Render(c, 200, Response{
+		Name: "batch",
+		JSON: gin.H{
+			"price":     price,
+			"timestamp": time.Now().UTC(),
+			"converted": len(results) - failed,
+			"failed":    failed,
+			"results":   results,
+		},
+		Fields: []Field{
+			{Name: "fiat", Value: fiat},
+			{Name: "converted", Value: strconv.Itoa(len(results) - failed)},
+			{Name: "failed", Value: strconv.Itoa(failed)},
+		},
+		Rows: rows,
+		Text: strconv.Itoa(len(results) - failed),
+	})
+}
+
+// parseBatch reads the items out of the body by its content type, JSON unless it's CSV or a file upload
+func parseBatch(c *gin.Context, body []byte, maxItems int) ([]lib.BatchItem, error) {
+	switch c.ContentType() {
+	case "text/csv":
+		return lib.ParseBatchCSV(bytes.NewReader(body), maxItems)
+	case "multipart/form-data":
+		contents, filename, fileErr := uploadedFile(c, body)
+		if fileErr != nil {
+			return nil, fileErr
+		}
+		if strings.HasSuffix(strings.ToLower(filename), ".json") {
+			return lib.ParseBatchJSON(bytes.NewReader(contents), maxItems)
+		}
+		return lib.ParseBatchCSV(bytes.NewReader(contents), maxItems)
+	}
+	return lib.ParseBatchJSON(bytes.NewReader(body), maxItems)
+}
+
+// readUpload reads the request body up to maxBytes, answering with a 413 or 400 itself when it can't
+func readUpload(c *gin.Context, maxBytes int64) (body []byte, ok bool) {
+	body, readErr := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(readErr, &tooLarge) {
+		RenderError(c, 413, fmt.Sprintf("upload can't be more than %d bytes", maxBytes))
+		return body, false
+	}
+	if readErr != nil {
+		RenderError(c, 400, "Problem reading the upload")
+		return body, false
+	}
+	return body, true
+}
+
+// uploadedFile is the multipart file called file in body
+func uploadedFile(c *gin.Context, body []byte) (contents []byte, filename string, err error) {
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	header, formErr := c.FormFile("file")
+	if formErr != nil {
+		return nil, "", errors.New("upload it as a file called file")
+	}
+	file, openErr := header.Open()
+	if openErr != nil {
+		return nil, "", openErr
+	}
+	defer file.Close()
+	contents, err = ioutil.ReadAll(file)
+	return contents, header.Filename, err
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	config "github.com/y4htse/turtle-utils/config"
+)
+
+func TestBatchConvertHandlerErrorsFollowTheRequestedFormat(t *testing.T) {
+	defer config.Apply(config.Current())
+	small := config.Default()
+	small.Batch.MaxBytes = 8
+	config.Apply(small)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/convert/batch", BatchConvertHandler)
+	req, _ := http.NewRequest(http.MethodPost, "/convert/batch?format=text", strings.NewReader("[1000, 2000, 3000]"))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, 413, recorder.Code)
+	assert.Equal(t, "upload can't be more than 8 bytes\n", recorder.Body.String())
+}
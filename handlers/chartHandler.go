@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+	svg "github.com/y4htse/turtle-utils/svg"
+)
+
+// chartRange is how far back a chart goes, how wide its candles are and how long it can be cached for
+type chartRange struct {
+	Name   string
+	Span   time.Duration
+	Candle time.Duration
+	MaxAge int
+}
+
+var chartRanges = []chartRange{
+	{Name: "24h", Span: 24 * time.Hour, Candle: time.Hour, MaxAge: 60},
+	{Name: "7d", Span: 7 * 24 * time.Hour, Candle: 6 * time.Hour, MaxAge: 300},
+	{Name: "30d", Span: 30 * 24 * time.Hour, Candle: 24 * time.Hour, MaxAge: 900},
+}
+
+// chartOptions are the query params both chart endpoints take
+type chartOptions struct {
+	Range    chartRange
+	Currency string
+	Kind     string
+}
+
+// Query is the options as query params for /chart.svg
+func (options chartOptions) Query() string {
+	return url.Values{
+		"range": {options.Range.Name},
+		"fiat":  {options.Currency},
+		"type":  {options.Kind},
+	}.Encode()
+}
+
+func parseChartOptions(c *gin.Context) (options chartOptions, err error) {
+	rangeName := c.DefaultQuery("range", "24h")
+	found := false
+	for _, candidate := range chartRanges {
+		if candidate.Name == rangeName {
+			options.Range, found = candidate, true
+		}
+	}
+	if !found {
+		return options, fmt.Errorf("range must be 24h, 7d or 30d, not %s", rangeName)
+	}
+
+	options.Currency = strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	if currencies := append([]string{"BTC"}, lib.Fiats()...); !contains(currencies, options.Currency) {
+		return options, fmt.Errorf("fiat must be one of %s", strings.Join(currencies, ", "))
+	}
+
+	options.Kind = c.DefaultQuery("type", "line")
+	if options.Kind != "line" && options.Kind != "candle" {
+		return options, fmt.Errorf("type must be line or candle, not %s", options.Kind)
+	}
+	return options, nil
+}
+
+// ChartHandler is a page showing the recorded price as a chart, with links to change the range and currency
+func ChartHandler(c *gin.Context) {
+	options, optionsErr := parseChartOptions(c)
+	if optionsErr != nil {
+		errHandler(400, optionsErr, c)
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	svgURL := "/chart.svg?" + options.Query()
+
+	c.HTML(http.StatusOK, "chart.tmpl", gin.H{
+		"options":    options,
+		"ranges":     chartRanges,
+		"currencies": append([]string{"BTC"}, lib.Fiats()...),
+		"kinds":      []string{"line", "candle"},
+		"svgURL":     svgURL,
+		"embed":      fmt.Sprintf("![TRTL price](%s://%s%s)", scheme, c.Request.Host, svgURL),
+	})
+}
+
+// ChartSVGHandler draws the recorded price over the range as an SVG line or candlestick chart
+// width and height can be set for embedding, and the image is cached for longer the longer the range
+func ChartSVGHandler(c *gin.Context) {
+	options, optionsErr := parseChartOptions(c)
+	if optionsErr != nil {
+		c.String(400, optionsErr.Error())
+		return
+	}
+	width, widthErr := strconv.Atoi(c.DefaultQuery("width", "600"))
+	height, heightErr := strconv.Atoi(c.DefaultQuery("height", "300"))
+	if widthErr != nil || heightErr != nil || width < 200 || width > 2000 || height < 100 || height > 1000 {
+		c.String(400, "width must be 200 to 2000 and height 100 to 1000")
+		return
+	}
+
+	history, historyErr := lib.GetPriceHistory()
+	if historyErr != nil {
+		c.String(500, "Problem opening the price history")
+		return
+	}
+
+	// to the minute so the same chart (and ETag) is served until there could be something new to draw
+	to := time.Now().UTC().Truncate(time.Minute)
+	from := to.Add(-options.Range.Span)
+	samples := lib.Samples(history.Range(from, to), options.Currency)
+	chart := svg.Chart{
+		Title:  fmt.Sprintf("TRTL/%s %s", options.Currency, options.Range.Name),
+		Width:  width,
+		Height: height,
+		From:   from,
+		To:     to,
+	}
+
+	var body []byte
+	if options.Kind == "candle" {
+		body = svg.Candlestick(chart, lib.Candles(samples, from, options.Range.Candle), options.Range.Candle)
+	} else {
+		body = svg.Line(chart, samples)
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", options.Range.MaxAge))
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(200, "image/svg+xml", body)
+}
@@ -2,25 +2,47 @@ package handlers
 
 import (
 	"fmt"
-	"log"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	config "github.com/y4htse/turtle-utils/config"
 	lib "github.com/y4htse/turtle-utils/lib"
 )
 
 // ConvertHandler will convert turtle coin (ints only) to BTC and USD
+// With ?at= it converts any amount at a past time using the price history instead
+// With ?to= it converts between any two assets through the market graph
 func ConvertHandler(c *gin.Context) {
-	trtl := c.DefaultQuery("trtl", "1")
+	trtl := c.DefaultQuery("trtl", c.DefaultQuery("amount", "1"))
+
+	if at := c.Query("at"); at != "" {
+		convertAt(c, trtl, at)
+		return
+	}
+
+	if to := c.Query("to"); to != "" {
+		if !turnedOff(c, "routes") {
+			convertRoute(c, trtl, c.DefaultQuery("from", "TRTL"), to)
+		}
+		return
+	}
 
 	trtlInt, intConvErr := strconv.ParseInt(trtl, 10, 64)
 
 	if intConvErr != nil {
-		c.JSON(404, gin.H{
-			"error": fmt.Sprintf("Problem converting %s to an int", trtl),
-		})
+		RenderError(c, 404, fmt.Sprintf("Problem converting %s to an int", trtl))
+		return
+	}
+
+	if quoteID := c.Query("quote"); quoteID != "" {
+		if !turnedOff(c, "quotes") {
+			convertWithQuote(c, quoteID, trtlInt)
+		}
+		return
 	}
 
 	forceCheck := c.DefaultQuery("force", "false")
@@ -30,18 +52,276 @@ func ConvertHandler(c *gin.Context) {
 		forcedBool = false
 	}
 
-	log.Printf("Forced - %t\n", forcedBool)
+	lib.Logger(c.Request.Context()).Debug("forced check", "forced", forcedBool)
+
+	if netBool, _ := strconv.ParseBool(c.DefaultQuery("net", "false")); netBool {
+		if !turnedOff(c, "fees") {
+			convertNet(c, trtlInt, forcedBool)
+		}
+		return
+	}
+
+	if c.Query("mode") == "orderbook" {
+		if !turnedOff(c, "orderBook") {
+			convertWithOrderBook(c, trtlInt, c.DefaultQuery("side", "sell"), forcedBool)
+		}
+		return
+	}
+
+	trtlValue, trtlConvertError := lib.ConvertTurtleContext(c.Request.Context(), trtlInt, forcedBool)
+
+	if trtlConvertError != nil {
+		RenderError(c, 500, errors.Wrap(trtlConvertError, "Could not convert at this time").Error())
+	} else {
+		Render(c, 200, priceResponse(c, trtlValue))
+	}
+
+}
+
+// turnedOff answers 404, like Feature does for a whole route, when a parameter asks for a feature that is off
+// rather than quietly converting some other way
+func turnedOff(c *gin.Context, name string) bool {
+	if config.Current().Features.Enabled(name) {
+		return false
+	}
+	RenderError(c, 404, name+" is turned off")
+	return true
+}
 
-	trtlValue, trtlConvertError := lib.ConvertTurtle(trtlInt, forcedBool)
+// convertWithQuote converts using the rates from a signed quote instead of pulling new ones
+func convertWithQuote(c *gin.Context, quoteID string, trtl int64) {
+	quote, parseErr := lib.ParseQuote(quoteID, lib.QuoteSecret())
+	if parseErr == lib.ErrQuoteExpired {
+		RenderError(c, 410, parseErr.Error())
+		return
+	}
+	if parseErr != nil {
+		RenderError(c, 400, parseErr.Error())
+		return
+	}
 
+	trtlValue, trtlConvertError := lib.ConvertTurtleQuote(quote, trtl)
 	if trtlConvertError != nil {
-		c.JSON(500, gin.H{
-			"errors": errors.Wrap(trtlConvertError, "Could not convert at this time"),
-		})
+		RenderError(c, 500, errors.Wrap(trtlConvertError, "Could not convert at this time").Error())
 	} else {
-		c.JSON(200, gin.H{
-			"price": trtlValue,
-		})
+		response := priceResponse(c, trtlValue)
+		response.JSON["quote"] = quote
+		response.Fields = append(response.Fields, Field{Name: "quote", Value: quote.ID})
+		Render(c, 200, response)
+	}
+}
+
+// convertWithOrderBook fills the amount against the tradeogre order book instead of using the last price
+func convertWithOrderBook(c *gin.Context, trtl int64, side string, forceCheck bool) {
+	if side != "buy" && side != "sell" {
+		RenderError(c, 400, fmt.Sprintf("side must be buy or sell, not %s", side))
+		return
+	}
+	if trtl <= 0 {
+		RenderError(c, 400, fmt.Sprintf("%d TRTL can't be filled, the amount must be more than 0", trtl))
+		return
+	}
+
+	fill, fillErr := lib.ConvertTurtleOrderBookContext(c.Request.Context(), trtl, side, forceCheck)
+	if fillErr != nil {
+		RenderError(c, 500, errors.Wrap(fillErr, "Could not convert at this time").Error())
+		return
+	}
+
+	text := Float(fill.UsdTotal)
+	if strings.ToLower(c.Query("currency")) == "btc" {
+		text = Float(fill.BtcTotal)
+	}
+	Render(c, 200, Response{
+		Name: "fill",
+		JSON: gin.H{
+			"fill": fill,
+		},
+		Fields: []Field{
+			{Name: "side", Value: fill.Side},
+			{Name: "requested", Value: Float(fill.Requested)},
+			{Name: "filled", Value: Float(fill.Filled)},
+			{Name: "unfilled", Value: Float(fill.Unfilled)},
+			{Name: "btcTotal", Value: Float(fill.BtcTotal)},
+			{Name: "usdTotal", Value: Float(fill.UsdTotal)},
+			{Name: "averagePrice", Value: Float(fill.AveragePrice)},
+			{Name: "spotPrice", Value: Float(fill.SpotPrice)},
+			{Name: "slippagePercent", Value: Float(fill.SlippagePercent)},
+		},
+		Text: text,
+	})
+}
+
+// convertNet works out what selling would actually leave after the network, trade and withdrawal fees
+func convertNet(c *gin.Context, trtl int64, forceCheck bool) {
+	mode := c.DefaultQuery("mode", lib.NetModeSpot)
+	if mode == lib.NetModeOrderBook && turnedOff(c, "orderBook") {
+		return
+	}
+	if mode != lib.NetModeSpot && mode != lib.NetModeOrderBook {
+		RenderError(c, 400, fmt.Sprintf("mode must be %s or %s, not %s", lib.NetModeSpot, lib.NetModeOrderBook, mode))
+		return
 	}
+	fiat := strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	if !contains(lib.Fiats(), fiat) {
+		RenderError(c, 400, fmt.Sprintf("fiat must be one of %s", strings.Join(lib.Fiats(), ", ")))
+		return
+	}
+	if _, sourceErr := lib.BtcSource(c.Query("source")); sourceErr != nil {
+		RenderError(c, 400, sourceErr.Error())
+		return
+	}
+	if networkFee := lib.CurrentSettings().NetworkFee; float64(trtl) <= networkFee {
+		RenderError(c, 400, fmt.Sprintf("%d TRTL doesn't cover the %s TRTL network fee", trtl, Float(networkFee)))
+		return
+	}
+
+	net, netErr := lib.ConvertTurtleNetContext(c.Request.Context(), float64(trtl), fiat, mode, c.Query("source"), forceCheck)
+	if errors.Cause(netErr) == lib.ErrBookTooThin {
+		RenderError(c, 422, netErr.Error())
+		return
+	}
+	if errors.Cause(netErr) == lib.ErrQuotesQuarantined {
+		RenderError(c, 503, netErr.Error())
+		return
+	}
+	if netErr != nil {
+		RenderError(c, 500, errors.Wrap(netErr, "Could not convert at this time").Error())
+		return
+	}
+
+	fields := []Field{
+		{Name: "trtl", Value: Float(net.Trtl)},
+		{Name: "source", Value: net.Source},
+		{Name: "mode", Value: net.Mode},
+		{Name: "fiat", Value: net.Fiat},
+		{Name: "gross", Value: Float(net.Gross)},
+	}
+	for _, line := range net.Fees {
+		fields = append(fields, Field{Name: line.Name + "Fee", Value: Float(line.Value)})
+	}
+	fields = append(fields, Field{Name: "net", Value: Float(net.Net)})
+	Render(c, 200, Response{
+		Name: "proceeds",
+		JSON: gin.H{
+			"proceeds": net,
+		},
+		Fields: fields,
+		Text:   Float(net.Net),
+	})
+}
+
+// convertAt converts using the recorded prices around a past time, interpolated or nearest per ?policy=
+func convertAt(c *gin.Context, amount string, at string) {
+	amountFloat, parseErr := strconv.ParseFloat(amount, 64)
+	if parseErr != nil || amountFloat < 0 || math.IsInf(amountFloat, 0) || math.IsNaN(amountFloat) {
+		RenderError(c, 400, fmt.Sprintf("%s is not an amount", amount))
+		return
+	}
+	atTime, timeErr := lib.ParseTime(at)
+	if timeErr != nil {
+		RenderError(c, 400, timeErr.Error())
+		return
+	}
+	if atTime.After(time.Now()) {
+		RenderError(c, 400, "at can't be in the future")
+		return
+	}
+	tolerance, toleranceErr := time.ParseDuration(c.DefaultQuery("tolerance", "24h"))
+	if toleranceErr != nil || tolerance <= 0 {
+		RenderError(c, 400, "tolerance must be a duration like 6h")
+		return
+	}
+	currency := strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	policy := c.DefaultQuery("policy", lib.PolicyInterpolate)
+
+	history, historyErr := lib.GetPriceHistory()
+	if historyErr != nil {
+		RenderError(c, 500, "Problem opening the price history")
+		return
+	}
+	price, priceErr := history.PriceAt(atTime, currency, policy, tolerance)
+	if errors.Cause(priceErr) == lib.ErrNoPriceAt {
+		RenderError(c, 404, priceErr.Error())
+		return
+	}
+	if priceErr != nil {
+		RenderError(c, 400, priceErr.Error())
+		return
+	}
+
+	value := amountFloat * price.Price
+	Render(c, 200, Response{
+		Name: "conversion",
+		JSON: gin.H{
+			"amount":     amountFloat,
+			"value":      value,
+			"historical": price,
+		},
+		Fields: []Field{
+			{Name: "amount", Value: Float(amountFloat)},
+			{Name: "currency", Value: currency},
+			{Name: "value", Value: Float(value)},
+			{Name: "price", Value: Float(price.Price)},
+			{Name: "at", Value: atTime.Format(time.RFC3339)},
+			{Name: "policy", Value: policy},
+		},
+		Text: Float(value),
+	})
+}
 
+// convertRoute converts along the best (or with ?route=liquid, the most liquid) path through the market graph
+func convertRoute(c *gin.Context, amount string, from string, to string) {
+	amountFloat, parseErr := strconv.ParseFloat(amount, 64)
+	if parseErr != nil || amountFloat < 0 || math.IsInf(amountFloat, 0) || math.IsNaN(amountFloat) {
+		RenderError(c, 400, fmt.Sprintf("%s is not an amount", amount))
+		return
+	}
+	prefer := strings.ToLower(c.DefaultQuery("route", lib.RouteBest))
+	forcedBool, parseBoolErr := strconv.ParseBool(strings.ToUpper(c.DefaultQuery("force", "false")))
+	if parseBoolErr != nil {
+		forcedBool = false
+	}
+
+	graph, graphErr := lib.GetMarketGraphContext(c.Request.Context(), forcedBool)
+	if graphErr != nil {
+		RenderError(c, 500, errors.Wrap(graphErr, "Could not convert at this time").Error())
+		return
+	}
+	route, routeErr := graph.FindRoute(from, to, prefer, lib.MaxRouteHops)
+	if errors.Cause(routeErr) == lib.ErrNoRoute {
+		RenderError(c, 404, fmt.Sprintf("%s, the markets have %s", routeErr.Error(), strings.Join(graph.Assets(), ", ")))
+		return
+	}
+	if routeErr != nil {
+		RenderError(c, 400, routeErr.Error())
+		return
+	}
+
+	value := amountFloat * route.Rate
+	path := []string{route.From}
+	for _, hop := range route.Hops {
+		path = append(path, hop.To)
+	}
+	fields := []Field{
+		{Name: "amount", Value: Float(amountFloat)},
+		{Name: "from", Value: route.From},
+		{Name: "to", Value: route.To},
+		{Name: "value", Value: Float(value)},
+		{Name: "rate", Value: Float(route.Rate)},
+		{Name: "route", Value: strings.Join(path, ">")},
+	}
+	if route.Liquidity != nil {
+		fields = append(fields, Field{Name: "liquidity", Value: Float(*route.Liquidity)})
+	}
+	Render(c, 200, Response{
+		Name: "conversion",
+		JSON: gin.H{
+			"amount": amountFloat,
+			"value":  value,
+			"route":  route,
+		},
+		Fields: fields,
+		Text:   Float(value),
+	})
 }
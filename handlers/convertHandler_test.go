@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	config "github.com/y4htse/turtle-utils/config"
+)
+
+func TestConvertHandlerTurnedOffFeatures(t *testing.T) {
+	defer config.Apply(config.Current())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/convert", ConvertHandler)
+	status := func(query string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/convert"+query, nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	turnedOff := config.Default()
+	turnedOff.Features.Quotes = false
+	turnedOff.Features.OrderBook = false
+	turnedOff.Features.Routes = false
+	turnedOff.Features.Fees = false
+	config.Apply(turnedOff)
+	assert.Equal(t, 404, status("?trtl=500&quote=abc"))
+	assert.Equal(t, 404, status("?trtl=500&mode=orderbook"))
+	assert.Equal(t, 404, status("?trtl=500&from=LTC&to=EUR"))
+	assert.Equal(t, 404, status("?trtl=500&net=true"))
+}
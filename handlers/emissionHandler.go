@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// maxProjectionHeight stops a projection from walking the chain for too long (roughly a century of blocks)
+const maxProjectionHeight = 100 * lib.BlocksPerYear
+
+// maxProjectionPoints keeps projection responses a sensible size
+const maxProjectionPoints = 1000
+
+// EmissionHandler returns the block reward, supply and inflation at ?height= (default is the estimated current height)
+func EmissionHandler(c *gin.Context) {
+	height, heightErr := parseHeight(c.Query("height"), lib.EstimateHeight(time.Now()))
+	if heightErr == nil && height > maxProjectionHeight {
+		heightErr = fmt.Errorf("height can't be more than %d", maxProjectionHeight)
+	}
+	if heightErr != nil {
+		c.JSON(400, gin.H{
+			"error": heightErr.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"emission": lib.EmissionAt(height),
+	})
+}
+
+// EmissionProjectionHandler projects the emission curve from now until ?until= (a date or a height)
+func EmissionProjectionHandler(c *gin.Context) {
+	fromHeight, fromErr := parseHeight(c.Query("from"), lib.EstimateHeight(time.Now()))
+	if fromErr != nil {
+		c.JSON(400, gin.H{
+			"error": fromErr.Error(),
+		})
+		return
+	}
+
+	until := c.Query("until")
+	if until == "" {
+		c.JSON(400, gin.H{
+			"error": "until must be a date like 2030-01-01 or a block height",
+		})
+		return
+	}
+	toHeight, untilErr := parseHeight(until, 0)
+	if untilErr != nil {
+		untilTime, timeErr := time.Parse("2006-01-02", until)
+		if timeErr != nil {
+			c.JSON(400, gin.H{
+				"error": "until must be a date like 2030-01-01 or a block height",
+			})
+			return
+		}
+		toHeight = lib.EstimateHeight(untilTime)
+	}
+	if toHeight < fromHeight || toHeight > maxProjectionHeight {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("until must be between height %d and %d", fromHeight, maxProjectionHeight),
+		})
+		return
+	}
+
+	step, stepErr := strconv.ParseUint(c.DefaultQuery("step", strconv.FormatUint(lib.BlocksPerYear, 10)), 10, 64)
+	if stepErr != nil || step == 0 {
+		c.JSON(400, gin.H{
+			"error": "step must be a positive number of blocks",
+		})
+		return
+	}
+	if (toHeight-fromHeight)/step > maxProjectionPoints {
+		step = (toHeight-fromHeight)/maxProjectionPoints + 1
+	}
+
+	c.JSON(200, gin.H{
+		"projection": lib.EmissionProjection(fromHeight, toHeight, step),
+	})
+}
+
+func parseHeight(height string, defaultHeight uint64) (uint64, error) {
+	if height == "" {
+		return defaultHeight, nil
+	}
+	parsed, err := strconv.ParseUint(height, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Problem converting %s to a height", height)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmissionHandlerBoundsHeight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/emission", EmissionHandler)
+	status := func(query string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/emission"+query, nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, 200, status("?height=1000"))
+	assert.Equal(t, 400, status("?height=18446744073709551615"))
+	assert.Equal(t, 400, status("?height=nope"))
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+	indicators "github.com/y4htse/turtle-utils/lib/indicators"
+)
+
+// IndicatorsHandler computes an indicator over candles of the recorded price
+// Intervals without any recorded price are gaps, the indicator is null until it has warmed up again after one
+// vwap weights candles by the traded volume recorded with the price, so intervals without any are gaps for it too
+func IndicatorsHandler(c *gin.Context) {
+	name := strings.ToLower(c.Query("name"))
+	if !contains(indicators.Names, name) {
+		RenderError(c, 400, fmt.Sprintf("name must be one of %s", strings.Join(indicators.Names, ", ")))
+		return
+	}
+	period, periodErr := strconv.Atoi(c.DefaultQuery("period", "14"))
+	if periodErr != nil || period < 1 || period > 500 {
+		RenderError(c, 400, "period must be a whole number from 1 to 500")
+		return
+	}
+	interval, intervalErr := time.ParseDuration(c.DefaultQuery("interval", "1h"))
+	if intervalErr != nil || interval < time.Minute || interval > 7*24*time.Hour {
+		RenderError(c, 400, "interval must be a duration from 1m to 168h")
+		return
+	}
+	limit, limitErr := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limitErr != nil || limit < 1 || limit > 1000 {
+		RenderError(c, 400, "limit must be a whole number from 1 to 1000")
+		return
+	}
+	currency := strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	if currencies := append([]string{"BTC"}, lib.Fiats()...); !contains(currencies, currency) {
+		RenderError(c, 400, fmt.Sprintf("fiat must be one of %s", strings.Join(currencies, ", ")))
+		return
+	}
+
+	history, historyErr := lib.GetPriceHistory()
+	if historyErr != nil {
+		RenderError(c, 500, "Problem opening the price history")
+		return
+	}
+
+	// enough candles before the ones returned for the indicator to warm up, EMA and RSI take a few periods to settle
+	to := time.Now().UTC()
+	first := to.Truncate(interval).Add(-interval * time.Duration(limit-1))
+	from := first.Add(-interval * time.Duration(3*period))
+	samples := lib.Samples(history.Range(from, to), currency)
+	if name == indicators.VWAP {
+		var traded []lib.PriceSample
+		for _, sample := range samples {
+			if sample.Volume > 0 {
+				traded = append(traded, sample)
+			}
+		}
+		samples = traded
+	}
+	candles := lib.Candles(samples, from, interval)
+
+	bars := make([]indicators.Bar, len(candles))
+	for i, candle := range candles {
+		bars[i] = indicators.Bar{
+			Time:   candle.Time,
+			High:   candle.High,
+			Low:    candle.Low,
+			Close:  candle.Close,
+			Volume: candle.Volume,
+		}
+	}
+	points, computeErr := indicators.Compute(name, bars, period, interval)
+	if computeErr != nil {
+		RenderError(c, 400, computeErr.Error())
+		return
+	}
+	for len(points) > 0 && points[0].Time.Before(first) {
+		points = points[1:]
+	}
+
+	text := ""
+	var rows [][]Field
+	for _, point := range points {
+		value := ""
+		if point.Value != nil {
+			value = Float(*point.Value)
+		}
+		text = value
+		rows = append(rows, []Field{
+			{Name: "time", Value: point.Time.Format(time.RFC3339)},
+			{Name: "value", Value: value},
+		})
+	}
+	Render(c, 200, Response{
+		Name: "indicator",
+		JSON: gin.H{
+			"name":     name,
+			"period":   period,
+			"interval": interval.String(),
+			"fiat":     currency,
+			"points":   points,
+		},
+		Rows: rows,
+		Text: text,
+	})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+func TestIndicatorsHandlerWeightsVWAPByRecordedVolume(t *testing.T) {
+	history, err := lib.GetPriceHistory()
+	assert.Nil(t, err)
+	hour := time.Now().UTC().Truncate(time.Hour)
+	history.Record(lib.PricePoint{Time: hour.Add(-2*time.Hour + time.Minute), TrtlToBtc: 1e-7, Volume: 1})
+	history.Record(lib.PricePoint{Time: hour.Add(-time.Hour + time.Minute), TrtlToBtc: 3e-7, Volume: 3})
+	// without a volume it's left out rather than counted as nothing traded
+	history.Record(lib.PricePoint{Time: hour.Add(-time.Hour + 2*time.Minute), TrtlToBtc: 9e-7})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/indicators", IndicatorsHandler)
+	req, _ := http.NewRequest(http.MethodGet, "/indicators?name=vwap&period=2&interval=1h&fiat=BTC&limit=3", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, 200, recorder.Code)
+
+	var body struct {
+		Points []struct {
+			Time  time.Time `json:"time"`
+			Value *float64  `json:"value"`
+		} `json:"points"`
+	}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	if assert.Len(t, body.Points, 2) {
+		assert.Nil(t, body.Points[0].Value)
+		if assert.NotNil(t, body.Points[1].Value) {
+			assert.InDelta(t, 2.5e-7, *body.Points[1].Value, 1e-15)
+		}
+	}
+}
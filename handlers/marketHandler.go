@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// MarketHandler returns the 24h open/high/low, change, volume and spread for every TRTL market
+func MarketHandler(c *gin.Context) {
+	fiat := strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	if !contains(lib.Fiats(), fiat) {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("fiat must be one of %s", strings.Join(lib.Fiats(), ", ")),
+		})
+		return
+	}
+	forceCheck := c.DefaultQuery("force", "false")
+
+	forcedBool, parseBoolErr := strconv.ParseBool(strings.ToUpper(forceCheck))
+	if parseBoolErr != nil {
+		forcedBool = false
+	}
+
+	lib.Logger(c.Request.Context()).Debug("forced check", "forced", forcedBool)
+	markets, err := lib.GetMarketStatsContext(c.Request.Context(), fiat, forcedBool)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error": "Problem getting the turtle market stats",
+		})
+	} else {
+		c.JSON(200, gin.H{
+			"markets": markets,
+		})
+	}
+}
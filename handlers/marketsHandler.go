@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	config "github.com/y4htse/turtle-utils/config"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// MarketsHandler lists every TRTL market side by side and the spreads between them worth arbitraging after fees
+// ?threshold= overrides the configured net spread percent
+func MarketsHandler(c *gin.Context) {
+	fiat := strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	if !contains(lib.Fiats(), fiat) {
+		RenderError(c, 400, fmt.Sprintf("fiat must be one of %s", strings.Join(lib.Fiats(), ", ")))
+		return
+	}
+	forcedBool, parseBoolErr := strconv.ParseBool(strings.ToUpper(c.DefaultQuery("force", "false")))
+	if parseBoolErr != nil {
+		forcedBool = false
+	}
+	threshold := config.Current().Arbitrage.Threshold
+	if text := c.Query("threshold"); text != "" {
+		parsed, parseErr := strconv.ParseFloat(text, 64)
+		if parseErr != nil || parsed < 0 {
+			RenderError(c, 400, "threshold must be a percent like 1.5")
+			return
+		}
+		threshold = parsed
+	}
+
+	comparison, err := lib.CompareMarketsContext(c.Request.Context(), fiat, threshold, forcedBool)
+	if err != nil {
+		RenderError(c, 500, "Problem getting the turtle market stats")
+		return
+	}
+
+	var rows [][]Field
+	for _, market := range comparison.Markets {
+		bid, ask := "", ""
+		if market.Bid != nil && market.Ask != nil {
+			bid, ask = Float(*market.Bid), Float(*market.Ask)
+		}
+		rows = append(rows, []Field{
+			{Name: "source", Value: market.Source},
+			{Name: "pair", Value: market.Pair},
+			{Name: "last", Value: Float(market.Last)},
+			{Name: "bid", Value: bid},
+			{Name: "ask", Value: ask},
+			{Name: "volume", Value: Float(market.Volume)},
+			{Name: "updatedAt", Value: market.UpdatedAt.Format(time.RFC3339)},
+		})
+	}
+	Render(c, 200, Response{
+		Name: "markets",
+		JSON: gin.H{
+			"markets":          comparison.Markets,
+			"thresholdPercent": comparison.ThresholdPercent,
+			"opportunities":    comparison.Opportunities,
+		},
+		Rows: rows,
+		Text: strconv.Itoa(len(comparison.Opportunities)),
+	})
+}
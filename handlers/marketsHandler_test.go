@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarketsHandlerRejectsAnUnknownFiat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/markets", MarketsHandler)
+	req, _ := http.NewRequest(http.MethodGet, "/markets?fiat=XYZ", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, 400, recorder.Code)
+}
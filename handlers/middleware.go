@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	config "github.com/y4htse/turtle-utils/config"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// validRequestID keeps incoming request IDs to something safe to echo back and log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID tags each request with the incoming X-Request-ID, or a new one when it is missing or odd looking,
+// puts it in the request's context for lib to log with and sends it back in the response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(lib.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// AccessLog logs each request once it has been served, in place of gin's default logger
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []interface{}{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", float64(time.Since(started).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+		}
+		switch {
+		case status >= 500:
+			lib.Logger(c.Request.Context()).Error("request", attrs...)
+		case status >= 400:
+			lib.Logger(c.Request.Context()).Warn("request", attrs...)
+		default:
+			lib.Logger(c.Request.Context()).Info("request", attrs...)
+		}
+	}
+}
+
+// Feature 404s requests to endpoints whose feature is turned off in the current config
+func Feature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.Current().Features.Enabled(name) {
+			RenderError(c, 404, name+" is turned off")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Admin lets a request through only with the configured admin token as a bearer token
+// The admin endpoints look like they don't exist when no token is configured
+func Admin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := config.Current().Admin.Token
+		if token == "" {
+			RenderError(c, 404, "admin endpoints are turned off, set admin.token")
+			c.Abort()
+			return
+		}
+		authorization := c.GetHeader("Authorization")
+		given := strings.TrimPrefix(authorization, "Bearer ")
+		if given == authorization || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			RenderError(c, 401, "admin token is missing or wrong")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// bucket is a client's token bucket, refilled continuously up to the burst size
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimit limits each client IP to the current config's requests per minute
+func RateLimit() gin.HandlerFunc {
+	var lock sync.Mutex
+	buckets := map[string]*bucket{}
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		limit := config.Current().RateLimit
+		if limit.RequestsPerMinute <= 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		perSecond := float64(limit.RequestsPerMinute) / 60
+		burst := float64(limit.Burst)
+
+		lock.Lock()
+		// forget clients that have been idle long enough to have a full bucket again
+		if now.Sub(lastSweep) > time.Minute {
+			for ip, clientBucket := range buckets {
+				if now.Sub(clientBucket.lastSeen).Seconds()*perSecond >= burst {
+					delete(buckets, ip)
+				}
+			}
+			lastSweep = now
+		}
+
+		clientBucket, ok := buckets[c.ClientIP()]
+		if !ok {
+			clientBucket = &bucket{tokens: burst, lastSeen: now}
+			buckets[c.ClientIP()] = clientBucket
+		}
+		clientBucket.tokens += now.Sub(clientBucket.lastSeen).Seconds() * perSecond
+		if clientBucket.tokens > burst {
+			clientBucket.tokens = burst
+		}
+		clientBucket.lastSeen = now
+		allowed := clientBucket.tokens >= 1
+		if allowed {
+			clientBucket.tokens--
+		}
+		retryAfter := math.Ceil((1 - clientBucket.tokens) / perSecond)
+		lock.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter)))
+			RenderError(c, 429, "Too many requests, slow down")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Deadline gives each request's context the current config's request timeout
+// so upstream calls are abandoned once the response can't be useful any more
+func Deadline() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := config.Current().Server.RequestTimeout
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
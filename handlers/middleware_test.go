@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	config "github.com/y4htse/turtle-utils/config"
+)
+
+func TestAdmin(t *testing.T) {
+	defer config.Apply(config.Current())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin", Admin(), func(c *gin.Context) {
+		c.Status(204)
+	})
+	status := func(authorization string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/admin", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	turnedOff := config.Default()
+	config.Apply(turnedOff)
+	assert.Equal(t, 404, status("Bearer anything"))
+
+	withToken := config.Default()
+	withToken.Admin.Token = "0123456789abcdef"
+	config.Apply(withToken)
+	assert.Equal(t, 401, status(""))
+	assert.Equal(t, 401, status("Bearer 0123456789abcdeX"))
+	assert.Equal(t, 401, status("0123456789abcdef"))
+	assert.Equal(t, 204, status("Bearer 0123456789abcdef"))
+}
+
+func TestAdminErrorsFollowTheRequestedFormat(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.Apply(config.Default())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin", Admin(), func(c *gin.Context) {
+		c.Status(204)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin?format=text", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, 404, recorder.Code)
+	assert.Equal(t, "admin endpoints are turned off, set admin.token\n", recorder.Body.String())
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// MiningProfitHandler estimates a rig's daily TRTL, revenue, power cost and profit
+func MiningProfitHandler(c *gin.Context) {
+	input := lib.MiningInput{
+		Fiat: strings.ToUpper(c.DefaultQuery("fiat", "USD")),
+	}
+	if !contains(lib.Fiats(), input.Fiat) {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("fiat must be one of %s", strings.Join(lib.Fiats(), ", ")),
+		})
+		return
+	}
+	floats := []struct {
+		name string
+		dest *float64
+	}{
+		{"hashrate", &input.Hashrate},
+		{"watts", &input.Watts},
+		{"power_cost", &input.PowerCost},
+	}
+	for _, field := range floats {
+		value := c.DefaultQuery(field.name, "0")
+		parsed, parseErr := strconv.ParseFloat(value, 64)
+		if parseErr != nil || parsed < 0 {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("Problem converting %s=%s to a positive number", field.name, value),
+			})
+			return
+		}
+		*field.dest = parsed
+	}
+	if input.Hashrate == 0 {
+		c.JSON(400, gin.H{
+			"error": "hashrate is required",
+		})
+		return
+	}
+
+	var heightErr error
+	input.Difficulty, heightErr = parseHeight(c.Query("difficulty"), 0)
+	if heightErr != nil {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("Problem converting difficulty=%s to an int", c.Query("difficulty")),
+		})
+		return
+	}
+	input.Height, heightErr = parseHeight(c.Query("height"), 0)
+	if heightErr == nil && input.Height > maxProjectionHeight {
+		heightErr = fmt.Errorf("height can't be more than %d", maxProjectionHeight)
+	}
+	if heightErr != nil {
+		c.JSON(400, gin.H{
+			"error": heightErr.Error(),
+		})
+		return
+	}
+
+	forceCheck := c.DefaultQuery("force", "false")
+
+	forcedBool, parseBoolErr := strconv.ParseBool(strings.ToUpper(forceCheck))
+	if parseBoolErr != nil {
+		forcedBool = false
+	}
+
+	lib.Logger(c.Request.Context()).Debug("forced check", "forced", forcedBool)
+	profit, err := lib.GetMiningProfitContext(c.Request.Context(), input, forcedBool)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"errors": err.Error(),
+		})
+	} else {
+		c.JSON(200, gin.H{
+			"profit": profit,
+		})
+	}
+}
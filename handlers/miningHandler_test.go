@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiningProfitHandlerRejectsBadInput(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/mining/profit", MiningProfitHandler)
+	status := func(query string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/mining/profit"+query, nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, 400, status("?hashrate=1000&difficulty=100&height=18446744073709551615"))
+	assert.Equal(t, 400, status("?hashrate=1000&difficulty=100&fiat=XYZ"))
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// OraclePriceHandler returns the current TRTL prices as signed attestations
+func OraclePriceHandler(c *gin.Context) {
+	if !lib.OracleEnabled() {
+		c.JSON(503, gin.H{
+			"error": lib.ErrOracleDisabled.Error(),
+		})
+		return
+	}
+
+	forceCheck := c.DefaultQuery("force", "false")
+
+	forcedBool, parseBoolErr := strconv.ParseBool(strings.ToUpper(forceCheck))
+	if parseBoolErr != nil {
+		forcedBool = false
+	}
+
+	lib.Logger(c.Request.Context()).Debug("forced check", "forced", forcedBool)
+	price, err := lib.GetPriceHashContext(c.Request.Context(), forcedBool)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error": "Problem getting the price of turtle in bitcoin",
+		})
+		return
+	}
+
+	attestations, attestErr := lib.AttestPrice(price)
+	if attestErr != nil {
+		c.JSON(500, gin.H{
+			"error": "Problem signing the price of turtle",
+		})
+		return
+	}
+	c.JSON(200, gin.H{
+		"attestations": attestations,
+	})
+}
+
+// OracleKeyHandler publishes the Ed25519 public key attestations are signed with
+func OracleKeyHandler(c *gin.Context) {
+	publicKey, err := lib.OraclePublicKey()
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(200, gin.H{
+		"algorithm": "ed25519",
+		"publicKey": base64.StdEncoding.EncodeToString(publicKey),
+	})
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"log"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	config "github.com/y4htse/turtle-utils/config"
 	lib "github.com/y4htse/turtle-utils/lib"
 )
 
@@ -18,15 +18,43 @@ func PriceHandler(c *gin.Context) {
 		forcedBool = false
 	}
 
-	log.Printf("Forced - %t\n", forcedBool)
-	price, err := lib.GetPriceHash(forcedBool)
+	lib.Logger(c.Request.Context()).Debug("forced check", "forced", forcedBool)
+	price, err := lib.GetPriceHashContext(c.Request.Context(), forcedBool)
 	if err != nil {
-		c.JSON(500, gin.H{
-			"error": "Problem getting the price of turtle in bitcoin",
-		})
-	} else {
-		c.JSON(200, gin.H{
+		RenderError(c, 500, "Problem getting the price of turtle in bitcoin")
+		return
+	}
+
+	response := priceResponse(c, price)
+	if !config.Current().Features.Oracle || !lib.OracleEnabled() {
+		Render(c, 200, response)
+		return
+	}
+
+	attestations, attestErr := lib.AttestPrice(price)
+	if attestErr != nil {
+		RenderError(c, 500, "Problem signing the price of turtle")
+		return
+	}
+	response.JSON["attestations"] = attestations
+	Render(c, 200, response)
+}
+
+// priceResponse is a price in every format, ?currency=btc makes the plain text value BTC instead of USD
+func priceResponse(c *gin.Context, price lib.CurrentPrice) Response {
+	text := Float(price.UsdPrice())
+	if strings.ToLower(c.Query("currency")) == "btc" {
+		text = Float(price.BtcPrice())
+	}
+	return Response{
+		Name: "price",
+		JSON: gin.H{
 			"price": price,
-		})
+		},
+		Fields: []Field{
+			{Name: "usd", Value: Float(price.UsdPrice())},
+			{Name: "btc", Value: Float(price.BtcPrice())},
+		},
+		Text: text,
 	}
 }
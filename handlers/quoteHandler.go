@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// QuoteHandler returns a signed quote that /convert?quote= can use until it expires
+func QuoteHandler(c *gin.Context) {
+	forceCheck := c.DefaultQuery("force", "false")
+
+	forcedBool, parseBoolErr := strconv.ParseBool(strings.ToUpper(forceCheck))
+	if parseBoolErr != nil {
+		forcedBool = false
+	}
+
+	lib.Logger(c.Request.Context()).Debug("forced check", "forced", forcedBool)
+	quote, err := lib.NewQuoteContext(c.Request.Context(), forcedBool)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error": "Problem getting a quote for turtle",
+		})
+	} else {
+		c.JSON(200, gin.H{
+			"quote": quote,
+		})
+	}
+}
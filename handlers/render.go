@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Formats are the response formats Render can write, picked with ?format= or the Accept header
+var Formats = map[string]string{
+	"json": "application/json; charset=utf-8",
+	"text": "text/plain; charset=utf-8",
+	"csv":  "text/csv; charset=utf-8",
+	"xml":  "application/xml; charset=utf-8",
+}
+
+// acceptFormats maps the media types clients ask for onto formats
+var acceptFormats = map[string]string{
+	"application/json": "json",
+	"text/plain":       "text",
+	"text/csv":         "csv",
+	"application/xml":  "xml",
+	"text/xml":         "xml",
+}
+
+// Field is one named value in a response's flat form
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Response is a handler's answer in every format it can be rendered in
+// JSON is the full body, Fields are the flat values used for CSV and XML rows, and Text is the single bare value
+// for text/plain so something like Google Sheets' IMPORTDATA gets just a number
+// Rows replaces Fields for answers that are a table, like a report with a line per year
+type Response struct {
+	Name   string
+	JSON   gin.H
+	Fields []Field
+	Rows   [][]Field
+	Text   string
+}
+
+// Float formats a number for the flat formats without exponents or trailing zeros
+func Float(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// responseFormat works out the format from ?format=, then the Accept header, falling back to JSON
+// Browsers ask for text/html first and accept anything, so both of those mean JSON and another format is only picked
+// when it's asked for ahead of them
+func responseFormat(c *gin.Context) (format string, err error) {
+	if format = strings.ToLower(c.Query("format")); format != "" {
+		if format == "txt" {
+			format = "text"
+		}
+		if _, ok := Formats[format]; !ok {
+			return "json", fmt.Errorf("format must be json, text, csv or xml, not %s", format)
+		}
+		return format, nil
+	}
+
+	for _, mediaType := range acceptedTypes(c.GetHeader("Accept")) {
+		if mediaType == "*/*" || mediaType == "text/html" {
+			return "json", nil
+		}
+		if acceptFormat, ok := acceptFormats[mediaType]; ok {
+			return acceptFormat, nil
+		}
+	}
+	return "json", nil
+}
+
+// acceptedTypes is the media types in an Accept header, most preferred first by their q-values
+// Types with q=0 aren't acceptable and are left out
+func acceptedTypes(accept string) []string {
+	type acceptedType struct {
+		mediaType string
+		quality   float64
+	}
+	var accepted []acceptedType
+	for _, entry := range strings.Split(accept, ",") {
+		mediaType, params, parseErr := mime.ParseMediaType(strings.TrimSpace(entry))
+		if parseErr != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			parsed, qErr := strconv.ParseFloat(q, 64)
+			if qErr != nil || parsed < 0 || parsed > 1 {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			accepted = append(accepted, acceptedType{mediaType: mediaType, quality: quality})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].quality > accepted[j].quality })
+
+	mediaTypes := make([]string, len(accepted))
+	for i, entry := range accepted {
+		mediaTypes[i] = entry.mediaType
+	}
+	return mediaTypes
+}
+
+// Render writes response in the format the client asked for
+func Render(c *gin.Context, status int, response Response) {
+	c.Header("Vary", "Accept")
+	format, formatErr := responseFormat(c)
+	if formatErr != nil {
+		c.JSON(400, gin.H{
+			"error": formatErr.Error(),
+		})
+		return
+	}
+
+	switch format {
+	case "text":
+		c.Data(status, Formats[format], []byte(response.Text+"\n"))
+	case "csv":
+		c.Data(status, Formats[format], renderCSV(response.rows()))
+	case "xml":
+		c.Data(status, Formats[format], renderXML(response.Name, response.Fields, response.Rows))
+	default:
+		c.JSON(status, response.JSON)
+	}
+}
+
+// RenderError writes an error message in the format the client asked for
+func RenderError(c *gin.Context, status int, message string) {
+	Render(c, status, Response{
+		Name:   "error",
+		JSON:   gin.H{"error": message},
+		Fields: []Field{{Name: "error", Value: message}},
+		Text:   message,
+	})
+}
+
+// rows is the response as a table, its one row of fields when it doesn't have rows
+func (response Response) rows() [][]Field {
+	if len(response.Rows) > 0 {
+		return response.Rows
+	}
+	return [][]Field{response.Fields}
+}
+
+// renderCSV writes a header row from the first row's names and then every row's values
+func renderCSV(rows [][]Field) []byte {
+	var body bytes.Buffer
+	writer := csv.NewWriter(&body)
+	for i, row := range rows {
+		names := make([]string, len(row))
+		values := make([]string, len(row))
+		for j, field := range row {
+			names[j] = field.Name
+			values[j] = field.Value
+		}
+		if i == 0 {
+			writer.Write(names)
+		}
+		writer.Write(values)
+	}
+	writer.Flush()
+	return body.Bytes()
+}
+
+// renderXML writes the fields as child elements of a root element called name, with each row in a row element
+func renderXML(name string, fields []Field, rows [][]Field) []byte {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&body)
+	root := xml.StartElement{Name: xml.Name{Local: name}}
+	encoder.EncodeToken(root)
+	for _, field := range fields {
+		encoder.EncodeElement(field.Value, xml.StartElement{Name: xml.Name{Local: field.Name}})
+	}
+	for _, row := range rows {
+		rowElement := xml.StartElement{Name: xml.Name{Local: "row"}}
+		encoder.EncodeToken(rowElement)
+		for _, field := range row {
+			encoder.EncodeElement(field.Value, xml.StartElement{Name: xml.Name{Local: field.Name}})
+		}
+		encoder.EncodeToken(rowElement.End())
+	}
+	encoder.EncodeToken(root.End())
+	encoder.Flush()
+	body.WriteString("\n")
+	return body.Bytes()
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func renderFor(target string, accept string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request, _ = http.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	Render(c, 200, Response{
+		Name:   "price",
+		JSON:   gin.H{"price": 0.5},
+		Fields: []Field{{Name: "usd", Value: Float(0.00001234)}, {Name: "btc", Value: Float(1.5e-7)}},
+		Text:   Float(0.00001234),
+	})
+	return recorder
+}
+
+func TestRenderFormats(t *testing.T) {
+	text := renderFor("/price?format=text", "")
+	assert.Equal(t, "0.00001234\n", text.Body.String())
+	assert.Contains(t, text.Header().Get("Content-Type"), "text/plain")
+
+	csv := renderFor("/price", "text/csv")
+	assert.Equal(t, "usd,btc\n0.00001234,0.00000015\n", csv.Body.String())
+
+	xml := renderFor("/price", "application/xml, text/html;q=0.9")
+	assert.Contains(t, xml.Body.String(), "<price><usd>0.00001234</usd><btc>0.00000015</btc></price>")
+
+	browser := renderFor("/price", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	assert.JSONEq(t, `{"price": 0.5}`, browser.Body.String())
+
+	json := renderFor("/price", "*/*")
+	assert.JSONEq(t, `{"price": 0.5}`, json.Body.String())
+
+	preferred := renderFor("/price", "text/csv;q=0.1, application/json")
+	assert.JSONEq(t, `{"price": 0.5}`, preferred.Body.String())
+
+	refused := renderFor("/price", "application/json;q=0, text/plain;q=0.5")
+	assert.Equal(t, "0.00001234\n", refused.Body.String())
+
+	bad := renderFor("/price?format=yaml", "")
+	assert.Equal(t, 400, bad.Code)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// SupplyHandler returns the circulating and max supply of TRTL
+func SupplyHandler(c *gin.Context) {
+	supply, err := lib.GetSupplyContext(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error": "Problem getting the supply of turtle",
+		})
+	} else {
+		c.JSON(200, gin.H{
+			"supply": supply,
+		})
+	}
+}
+
+// MarketCapHandler returns the market cap and fully diluted value in BTC and the configured fiats
+func MarketCapHandler(c *gin.Context) {
+	forceCheck := c.DefaultQuery("force", "false")
+
+	forcedBool, parseBoolErr := strconv.ParseBool(strings.ToUpper(forceCheck))
+	if parseBoolErr != nil {
+		forcedBool = false
+	}
+
+	lib.Logger(c.Request.Context()).Debug("forced check", "forced", forcedBool)
+	supply, caps, err := lib.GetMarketCapContext(c.Request.Context(), forcedBool)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error": "Problem getting the market cap of turtle",
+		})
+	} else {
+		c.JSON(200, gin.H{
+			"supply":    supply,
+			"marketCap": caps,
+		})
+	}
+}
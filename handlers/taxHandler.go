@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	config "github.com/y4htse/turtle-utils/config"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// TaxReportHandler values a CSV ledger of TRTL transactions at their historical prices and reports the realized
+// gain or loss per year, with ?method= fifo, lifo or average cost basis
+// The ledger is the request body (Content-Type: text/csv) or a multipart upload called file
+func TaxReportHandler(c *gin.Context) {
+	fiat := strings.ToUpper(c.DefaultQuery("fiat", "USD"))
+	method := strings.ToLower(c.DefaultQuery("method", lib.MethodFIFO))
+	policy := c.DefaultQuery("policy", lib.PolicyInterpolate)
+	tolerance, toleranceErr := time.ParseDuration(c.DefaultQuery("tolerance", "24h"))
+	if toleranceErr != nil || tolerance <= 0 {
+		RenderError(c, 400, "tolerance must be a duration like 6h")
+		return
+	}
+
+	body, ok := readUpload(c, config.Current().Batch.MaxBytes)
+	if !ok {
+		return
+	}
+	if c.ContentType() == "multipart/form-data" {
+		contents, _, fileErr := uploadedFile(c, body)
+		if fileErr != nil {
+			RenderError(c, 400, fileErr.Error())
+			return
+		}
+		body = contents
+	}
+
+	transactions, parseErr := lib.ParseLedgerCSV(bytes.NewReader(body))
+	if parseErr != nil {
+		RenderError(c, 400, parseErr.Error())
+		return
+	}
+	history, historyErr := lib.GetPriceHistory()
+	if historyErr != nil {
+		RenderError(c, 500, "Problem opening the price history")
+		return
+	}
+	if valueErr := history.ValueTransactions(transactions, fiat, policy, tolerance); valueErr != nil {
+		status := 400
+		if errors.Cause(valueErr) == lib.ErrNoPriceAt {
+			status = 422
+		}
+		RenderError(c, status, valueErr.Error())
+		return
+	}
+
+	report, reportErr := lib.NewTaxReport(transactions, fiat, method)
+	if reportErr != nil {
+		RenderError(c, 400, reportErr.Error())
+		return
+	}
+
+	total := 0.0
+	var rows [][]Field
+	for _, year := range report.Years {
+		total += year.Gain
+		rows = append(rows, []Field{
+			{Name: "year", Value: strconv.Itoa(year.Year)},
+			{Name: "proceeds", Value: Float(year.Proceeds)},
+			{Name: "costBasis", Value: Float(year.CostBasis)},
+			{Name: "gain", Value: Float(year.Gain)},
+		})
+	}
+	Render(c, 200, Response{
+		Name: "report",
+		JSON: gin.H{
+			"report": report,
+		},
+		Fields: []Field{
+			{Name: "fiat", Value: report.Fiat},
+			{Name: "method", Value: report.Method},
+		},
+		Rows: rows,
+		Text: Float(total),
+	})
+}
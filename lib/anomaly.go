@@ -0,0 +1,206 @@
+package lib
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxQuarantined is how many quarantined quotes are kept for the admin endpoint
+const maxQuarantined = 500
+
+// confirmTolerance is how close, as a percent, a suspicious quote has to be to the last one from the same source to
+// count as confirming it
+const confirmTolerance = 5.0
+
+// AnomalySettings are the thresholds a new quote is checked against before it's published, zero turns a check off
+// ZScore and JumpPercent compare it with the prices recorded in the last Window, once there are MinSamples of them
+// DivergencePercent compares it with the median of every source's quote, when there are at least three
+// A source that keeps quoting the same suspicious price is believed once it has been quarantined Confirmations times
+// in a row over at least ConfirmFor, it's a real move. A fat-finger print stays an exchange's last price until the
+// next trade, so counting pulls alone would believe it after a few quick requests
+type AnomalySettings struct {
+	Window            time.Duration
+	MinSamples        int
+	ZScore            float64
+	JumpPercent       float64
+	DivergencePercent float64
+	Confirmations     int
+	ConfirmFor        time.Duration
+}
+
+// QuarantinedQuote is a quote that wasn't published because it looked wrong
+// Reference is what it was compared with: the recent mean, the last recorded price or the median quote
+type QuarantinedQuote struct {
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Pair      string    `json:"pair"`
+	Price     float64   `json:"price"`
+	Reason    string    `json:"reason"`
+	Reference float64   `json:"reference"`
+}
+
+// sourceQuote is one source's price from a pull
+type sourceQuote struct {
+	source Source
+	price  float64
+	volume float64
+}
+
+// suspect is a source's run of suspicious quotes since it started, for confirming a real move
+type suspect struct {
+	price float64
+	count int
+	since time.Time
+}
+
+var quarantine = struct {
+	lock     sync.Mutex
+	quotes   []QuarantinedQuote
+	suspects map[string]suspect
+}{suspects: map[string]suspect{}}
+
+// Quarantine is the quotes that were held back, newest first
+func Quarantine() []QuarantinedQuote {
+	quarantine.lock.Lock()
+	defer quarantine.lock.Unlock()
+	quotes := make([]QuarantinedQuote, len(quarantine.quotes))
+	for i, quote := range quarantine.quotes {
+		quotes[len(quotes)-1-i] = quote
+	}
+	return quotes
+}
+
+// ClearQuarantine forgets the quarantined quotes and any moves waiting to be confirmed
+func ClearQuarantine() {
+	quarantine.lock.Lock()
+	defer quarantine.lock.Unlock()
+	quarantine.quotes = nil
+	quarantine.suspects = map[string]suspect{}
+}
+
+// recentBtcSamples are the TRTL-BTC prices recorded in the anomaly window
+func recentBtcSamples(anomaly AnomalySettings) []PriceSample {
+	history, historyErr := GetPriceHistory()
+	if historyErr != nil || anomaly.Window <= 0 {
+		return nil
+	}
+	now := time.Now()
+	return Samples(history.Range(now.Add(-anomaly.Window), now), "BTC")
+}
+
+// screenQuotes checks each quote against the recent samples and the other quotes, quarantining the suspicious ones
+func screenQuotes(ctx context.Context, quotes []sourceQuote, recent []PriceSample) (accepted []sourceQuote) {
+	anomaly := CurrentSettings().Anomaly
+	prices := make([]float64, len(quotes))
+	for i, quote := range quotes {
+		prices[i] = quote.price
+	}
+
+	quarantine.lock.Lock()
+	defer quarantine.lock.Unlock()
+	for _, quote := range quotes {
+		key := quote.source.Name + " " + quote.source.Pair
+		reason, reference := quoteSuspicion(quote.price, prices, recent, anomaly)
+		if reason == "" {
+			delete(quarantine.suspects, key)
+			accepted = append(accepted, quote)
+			continue
+		}
+
+		// the same price again and again is more likely a real move than another fat finger
+		// divergence is never confirmed away since the other sources are saying what the price is right now
+		previous, seen := quarantine.suspects[key]
+		run := suspect{price: quote.price, count: 1, since: time.Now()}
+		if seen && math.Abs(quote.price-previous.price)/previous.price*100 <= confirmTolerance {
+			run.count, run.since = previous.count+1, previous.since
+		}
+		if reason != "divergence" && anomaly.Confirmations > 0 && run.count > anomaly.Confirmations && time.Since(run.since) >= anomaly.ConfirmFor {
+			Logger(ctx).Warn("Believing a suspicious quote after it was confirmed", "source", quote.source.Name, "price", quote.price, "reason", reason, "confirmations", anomaly.Confirmations)
+			delete(quarantine.suspects, key)
+			accepted = append(accepted, quote)
+			continue
+		}
+		quarantine.suspects[key] = run
+
+		Logger(ctx).Warn("Quarantined a suspicious quote", "source", quote.source.Name, "pair", quote.source.Pair, "price", quote.price, "reason", reason, "reference", reference)
+		quarantine.quotes = append(quarantine.quotes, QuarantinedQuote{
+			Time:      time.Now().UTC(),
+			Source:    quote.source.Name,
+			Pair:      quote.source.Pair,
+			Price:     quote.price,
+			Reason:    reason,
+			Reference: reference,
+		})
+		if len(quarantine.quotes) > maxQuarantined {
+			quarantine.quotes = quarantine.quotes[len(quarantine.quotes)-maxQuarantined:]
+		}
+	}
+	return accepted
+}
+
+// checkQuotes is the quotes that don't look wrong, without quarantining the others or counting them towards
+// confirming a move. It's for showing the same markets the price pull screens, which does the quarantining
+func checkQuotes(quotes []sourceQuote, recent []PriceSample) (accepted []sourceQuote) {
+	anomaly := CurrentSettings().Anomaly
+	prices := make([]float64, len(quotes))
+	for i, quote := range quotes {
+		prices[i] = quote.price
+	}
+	for _, quote := range quotes {
+		if reason, _ := quoteSuspicion(quote.price, prices, recent, anomaly); reason == "" {
+			accepted = append(accepted, quote)
+		}
+	}
+	return accepted
+}
+
+// quoteSuspicion is why price looks wrong next to every quote in the pull and the recent samples, "" when it doesn't,
+// and what it was compared with
+func quoteSuspicion(price float64, quotes []float64, recent []PriceSample, anomaly AnomalySettings) (reason string, reference float64) {
+	if anomaly.DivergencePercent > 0 && len(quotes) >= 3 {
+		median := median(quotes)
+		if median > 0 && math.Abs(price-median)/median*100 > anomaly.DivergencePercent {
+			return "divergence", median
+		}
+	}
+	if len(recent) == 0 || len(recent) < anomaly.MinSamples {
+		return "", 0
+	}
+
+	last := recent[len(recent)-1].Price
+	if anomaly.JumpPercent > 0 && last > 0 && math.Abs(price-last)/last*100 > anomaly.JumpPercent {
+		return "jump", last
+	}
+	if anomaly.ZScore > 0 {
+		mean, deviation := meanDeviation(recent)
+		if deviation > 0 && math.Abs(price-mean)/deviation > anomaly.ZScore {
+			return "z-score", mean
+		}
+	}
+	return "", 0
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// meanDeviation is the mean and population standard deviation of the sample prices
+func meanDeviation(samples []PriceSample) (mean float64, deviation float64) {
+	for _, sample := range samples {
+		mean += sample.Price
+	}
+	mean /= float64(len(samples))
+	for _, sample := range samples {
+		deviation += (sample.Price - mean) * (sample.Price - mean)
+	}
+	return mean, math.Sqrt(deviation / float64(len(samples)))
+}
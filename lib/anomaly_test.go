@@ -0,0 +1,188 @@
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func steadySamples(n int, price float64) (samples []PriceSample) {
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		// a little noise so the deviation isn't zero
+		samples = append(samples, PriceSample{Time: start.Add(time.Duration(i) * time.Minute), Price: price * (1 + 0.01*float64(i%3-1))})
+	}
+	return samples
+}
+
+func TestQuoteSuspicion(t *testing.T) {
+	anomaly := AnomalySettings{MinSamples: 10, ZScore: 4, JumpPercent: 50, DivergencePercent: 25}
+	recent := steadySamples(20, 100)
+
+	reason, _ := quoteSuspicion(101, []float64{101}, recent, anomaly)
+	assert.Equal(t, "", reason)
+
+	reason, reference := quoteSuspicion(500, []float64{500}, recent, anomaly)
+	assert.Equal(t, "jump", reason)
+	assert.Equal(t, recent[19].Price, reference)
+
+	reason, reference = quoteSuspicion(110, []float64{110}, recent, anomaly)
+	assert.Equal(t, "z-score", reason)
+	assert.InDelta(t, 100, reference, 0.1)
+
+	reason, reference = quoteSuspicion(150, []float64{100, 102, 150}, nil, anomaly)
+	assert.Equal(t, "divergence", reason)
+	assert.Equal(t, 102.0, reference)
+
+	reason, _ = quoteSuspicion(150, []float64{100, 150}, nil, anomaly)
+	assert.Equal(t, "", reason, "two sources can't say which one is wrong")
+	reason, _ = quoteSuspicion(500, []float64{500}, recent[:5], anomaly)
+	assert.Equal(t, "", reason, "not enough history to judge")
+	reason, _ = quoteSuspicion(500, []float64{500}, recent, AnomalySettings{})
+	assert.Equal(t, "", reason, "every check turned off")
+}
+
+func TestPullTrtlToBtcPriceQuarantinesAFatFinger(t *testing.T) {
+	normal := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl.json"})
+	defer normal.Close()
+	fatFinger := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl_rich.json"})
+	defer fatFinger.Close()
+
+	defer Configure(DefaultSettings())
+	defer ClearQuarantine()
+	settings := DefaultSettings()
+	settings.Anomaly = AnomalySettings{DivergencePercent: 25}
+	settings.Sources = []Source{
+		{Name: "a", Kind: "tradeogre", URL: normal.URL, Pair: "BTC-TRTL", Weight: 1},
+		{Name: "b", Kind: "tradeogre", URL: normal.URL, Pair: "BTC-TRTL", Weight: 1},
+		{Name: "c", Kind: "tradeogre", URL: fatFinger.URL, Pair: "BTC-TRTL", Weight: 1},
+	}
+	Configure(settings)
+
+	price, sources, err := PullTrtlToBtcPriceContext(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0.00000005, price)
+	assert.Equal(t, []string{"a:BTC-TRTL", "b:BTC-TRTL"}, sources, "only the sources that were averaged")
+
+	quarantined := Quarantine()
+	assert.Len(t, quarantined, 1)
+	assert.Equal(t, "c", quarantined[0].Source)
+	assert.Equal(t, 0.00000008, quarantined[0].Price)
+	assert.Equal(t, "divergence", quarantined[0].Reason)
+
+	ClearQuarantine()
+	assert.Empty(t, Quarantine())
+}
+
+func TestScreenQuotesBelievesAConfirmedMove(t *testing.T) {
+	recent := steadySamples(10, 0.0000001)
+	defer Configure(DefaultSettings())
+	defer ClearQuarantine()
+	settings := DefaultSettings()
+	settings.Anomaly = AnomalySettings{MinSamples: 10, JumpPercent: 50, Confirmations: 2}
+	Configure(settings)
+
+	quotes := []sourceQuote{{source: Source{Name: "tradeogre", Pair: "BTC-TRTL"}, price: 0.0000005}}
+	assert.Empty(t, screenQuotes(context.Background(), quotes, recent))
+	assert.Empty(t, screenQuotes(context.Background(), quotes, recent))
+	assert.Len(t, screenQuotes(context.Background(), quotes, recent), 1, "believed the third time")
+	assert.Len(t, Quarantine(), 2)
+	assert.Equal(t, "jump", Quarantine()[0].Reason)
+}
+
+func TestScreenQuotesWaitsBeforeBelievingAMove(t *testing.T) {
+	recent := steadySamples(10, 0.0000001)
+	defer Configure(DefaultSettings())
+	defer ClearQuarantine()
+	settings := DefaultSettings()
+	settings.Anomaly = AnomalySettings{MinSamples: 10, JumpPercent: 50, Confirmations: 2, ConfirmFor: time.Hour}
+	Configure(settings)
+
+	// a fat-finger print is still the last price on every quick pull, that isn't the market moving
+	quotes := []sourceQuote{{source: Source{Name: "tradeogre", Pair: "BTC-TRTL"}, price: 0.0000005}}
+	for i := 0; i < 5; i++ {
+		assert.Empty(t, screenQuotes(context.Background(), quotes, recent))
+	}
+
+	quarantine.lock.Lock()
+	run := quarantine.suspects["tradeogre BTC-TRTL"]
+	run.since = run.since.Add(-2 * time.Hour)
+	quarantine.suspects["tradeogre BTC-TRTL"] = run
+	quarantine.lock.Unlock()
+	assert.Len(t, screenQuotes(context.Background(), quotes, recent), 1, "believed once it has lasted")
+}
+
+func TestMarketsAndRoutesLeaveOutAFatFinger(t *testing.T) {
+	normal := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl.json"})
+	defer normal.Close()
+	fatFinger := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl_rich.json"})
+	defer fatFinger.Close()
+
+	defer Configure(DefaultSettings())
+	defer ClearQuarantine()
+	settings := DefaultSettings()
+	settings.Fiats = nil
+	settings.Anomaly = AnomalySettings{DivergencePercent: 25}
+	settings.Sources = []Source{
+		{Name: "a", Kind: "tradeogre", URL: normal.URL, Pair: "BTC-TRTL", Weight: 1},
+		{Name: "b", Kind: "tradeogre", URL: normal.URL, Pair: "BTC-TRTL", Weight: 1},
+		{Name: "c", Kind: "tradeogre", URL: fatFinger.URL, Pair: "BTC-TRTL", Weight: 1},
+	}
+	Configure(settings)
+
+	markets, err := PullMarkets(true)
+	assert.Nil(t, err)
+	assert.Len(t, markets, 2)
+	for _, market := range markets {
+		assert.NotEqual(t, "c", market.Source)
+	}
+
+	graph, err := GetMarketGraph(true)
+	assert.Nil(t, err)
+	for _, edges := range graph.edges {
+		for _, edge := range edges {
+			assert.NotEqual(t, "c", edge.Source)
+		}
+	}
+	assert.Empty(t, Quarantine(), "only the price pull quarantines")
+}
+
+func TestEveryQuoteQuarantinedServesTheLastAcceptedPrice(t *testing.T) {
+	fatFinger := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl_rich.json"})
+	defer fatFinger.Close()
+	coinbase := fixtureExchange(t, map[string]string{"/prices/BTC-USD/spot": "coinbase_spot_btc_usd.json"})
+	defer coinbase.Close()
+
+	defer Configure(DefaultSettings())
+	defer ClearQuarantine()
+	settings := DefaultSettings()
+	settings.FiatURL = coinbase.URL
+	settings.Anomaly = AnomalySettings{Window: time.Hour, MinSamples: 1, JumpPercent: 50}
+	settings.Sources = []Source{{Name: "a", Kind: "tradeogre", URL: fatFinger.URL, Pair: "BTC-TRTL", Weight: 1}}
+	Configure(settings)
+
+	history, _ := GetPriceHistory()
+	history.Record(PricePoint{Time: time.Now().UTC(), TrtlToBtc: 0.00000002})
+	acceptedAt := time.Now().Add(-time.Hour)
+	prices.setQuote("BTC-TRTL", btcQuote{price: 0.00000002, fetchedAt: acceptedAt, sources: []string{"a:BTC-TRTL"}})
+
+	quote, err := getTrtlToBtcQuote(context.Background(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.00000002, quote.price)
+	assert.True(t, quote.fetchedAt.Equal(acceptedAt), "it keeps when it was fetched so it shows as stale")
+	assert.True(t, quote.stale)
+	assert.Len(t, Quarantine(), 1)
+
+	// it was recorded when it was accepted, recording it again would make it look fresh
+	recorded := len(history.Range(acceptedAt, time.Now()))
+	price, err := GetFiatPriceHash("USD", true)
+	assert.Nil(t, err)
+	assert.True(t, price.Stale())
+	assert.Len(t, history.Range(acceptedAt, time.Now()), recorded)
+
+	prices.clear()
+	_, err = getTrtlToBtcQuote(context.Background(), true)
+	assert.Equal(t, ErrQuotesQuarantined, err)
+}
@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"context"
+	"sort"
+)
+
+// Opportunity is buying TRTL at one exchange's ask and selling it at another's bid for the same pair
+// NetSpreadPercent is after both exchanges' trade fees; network and withdrawal fees are fixed amounts, so they
+// depend on the size of the trade and aren't included
+type Opportunity struct {
+	Pair             string  `json:"pair"`
+	BuySource        string  `json:"buySource"`
+	BuyPrice         float64 `json:"buyPrice"`
+	SellSource       string  `json:"sellSource"`
+	SellPrice        float64 `json:"sellPrice"`
+	SpreadPercent    float64 `json:"spreadPercent"`
+	NetSpreadPercent float64 `json:"netSpreadPercent"`
+}
+
+// MarketComparison is every TRTL market side by side and the spreads between them above the threshold
+type MarketComparison struct {
+	Markets          []MarketStats `json:"markets"`
+	ThresholdPercent float64       `json:"thresholdPercent"`
+	Opportunities    []Opportunity `json:"opportunities"`
+}
+
+// CompareMarkets pulls every TRTL market and looks for arbitrage between them
+// An empty fiat leaves the volumes unvalued, the spreads only need the markets' own prices
+func CompareMarkets(fiat string, thresholdPercent float64, forceCheck bool) (comparison MarketComparison, err error) {
+	return CompareMarketsContext(context.Background(), fiat, thresholdPercent, forceCheck)
+}
+
+// CompareMarketsContext is CompareMarkets that gives up when ctx is done
+func CompareMarketsContext(ctx context.Context, fiat string, thresholdPercent float64, forceCheck bool) (comparison MarketComparison, err error) {
+	var markets []MarketStats
+	var marketsErr error
+	if fiat == "" {
+		markets, marketsErr = PullMarketsContext(ctx, forceCheck)
+	} else {
+		markets, marketsErr = GetMarketStatsContext(ctx, fiat, forceCheck)
+	}
+	if marketsErr != nil {
+		return comparison, marketsErr
+	}
+	return MarketComparison{
+		Markets:          markets,
+		ThresholdPercent: thresholdPercent,
+		Opportunities:    FindArbitrage(markets, CurrentSettings().Sources, thresholdPercent),
+	}, nil
+}
+
+// FindArbitrage compares every pair of markets for the same pair and keeps the ones whose spread after trade fees
+// is more than thresholdPercent, best first. Markets without a book are taken at their last trade
+func FindArbitrage(markets []MarketStats, sources []Source, thresholdPercent float64) (opportunities []Opportunity) {
+	fees := map[string]FeeSchedule{}
+	for _, source := range sources {
+		fees[source.Name+" "+source.Pair] = source.Fees
+	}
+
+	opportunities = []Opportunity{}
+	for _, buy := range markets {
+		for _, sell := range markets {
+			if buy.Source == sell.Source || buy.Pair != sell.Pair {
+				continue
+			}
+			buyPrice, sellPrice := buy.Last, sell.Last
+			if buy.Ask != nil && *buy.Ask > 0 {
+				buyPrice = *buy.Ask
+			}
+			if sell.Bid != nil && *sell.Bid > 0 {
+				sellPrice = *sell.Bid
+			}
+			if buyPrice <= 0 || sellPrice <= buyPrice {
+				continue
+			}
+
+			cost := buyPrice * (1 + fees[buy.Source+" "+buy.Pair].TradePercent/100)
+			proceeds := sellPrice * (1 - fees[sell.Source+" "+sell.Pair].TradePercent/100)
+			opportunity := Opportunity{
+				Pair:             buy.Pair,
+				BuySource:        buy.Source,
+				BuyPrice:         buyPrice,
+				SellSource:       sell.Source,
+				SellPrice:        sellPrice,
+				SpreadPercent:    (sellPrice - buyPrice) / buyPrice * 100,
+				NetSpreadPercent: (proceeds - cost) / cost * 100,
+			}
+			if opportunity.NetSpreadPercent > thresholdPercent {
+				opportunities = append(opportunities, opportunity)
+			}
+		}
+	}
+
+	sort.Slice(opportunities, func(i, j int) bool {
+		return opportunities[i].NetSpreadPercent > opportunities[j].NetSpreadPercent
+	})
+	return opportunities
+}
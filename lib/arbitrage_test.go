@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureExchange stands in for an exchange, answering each path with a file from testdata
+func fixtureExchange(t *testing.T, fixtures map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body, err := ioutil.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Error(err)
+		}
+		w.Write(body)
+	}))
+}
+
+func TestCompareMarkets(t *testing.T) {
+	cheap := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl.json"})
+	defer cheap.Close()
+	rich := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl_rich.json"})
+	defer rich.Close()
+	coinbase := fixtureExchange(t, map[string]string{"/prices/BTC-USD/spot": "coinbase_spot_btc_usd.json"})
+	defer coinbase.Close()
+
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.FiatURL = coinbase.URL
+	settings.Sources = []Source{
+		{Name: "cheap", Kind: "tradeogre", URL: cheap.URL, Pair: "BTC-TRTL", Weight: 1, Fees: FeeSchedule{TradePercent: 0.2}},
+		{Name: "rich", Kind: "tradeogre", URL: rich.URL, Pair: "BTC-TRTL", Weight: 1, Fees: FeeSchedule{TradePercent: 0.2}},
+	}
+	Configure(settings)
+
+	comparison, err := CompareMarkets("USD", 5, true)
+	assert.Nil(t, err)
+	assert.Len(t, comparison.Markets, 2)
+	assert.InDelta(t, 171863.0467, comparison.Markets[0].FiatVolume, 1e-6)
+	// buy at cheap's 5 sat ask and sell at rich's 7 sat bid
+	assert.Len(t, comparison.Opportunities, 1)
+	opportunity := comparison.Opportunities[0]
+	assert.Equal(t, "cheap", opportunity.BuySource)
+	assert.Equal(t, "rich", opportunity.SellSource)
+	assert.InDelta(t, 40.0, opportunity.SpreadPercent, 1e-9)
+	assert.InDelta(t, (0.00000007*0.998-0.00000005*1.002)/(0.00000005*1.002)*100, opportunity.NetSpreadPercent, 1e-9)
+
+	comparison, err = CompareMarkets("USD", 50, true)
+	assert.Nil(t, err)
+	assert.Empty(t, comparison.Opportunities)
+
+	// the spreads don't need a BTC price, so they still work without one
+	coinbase.Close()
+	comparison, err = CompareMarkets("", 5, true)
+	assert.Nil(t, err)
+	assert.Len(t, comparison.Opportunities, 1)
+	assert.Empty(t, comparison.Markets[0].Fiat)
+	_, err = CompareMarkets("USD", 5, true)
+	assert.NotNil(t, err)
+}
+
+func TestFindArbitrageFeesEatTheSpread(t *testing.T) {
+	bid, ask := 0.000000101, 0.0000001
+	markets := []MarketStats{
+		{Source: "a", Pair: "BTC-TRTL", Last: ask, Ask: &ask},
+		{Source: "b", Pair: "BTC-TRTL", Last: bid, Bid: &bid},
+		{Source: "c", Pair: "LTC-TRTL", Last: 1},
+	}
+	sources := []Source{
+		{Name: "a", Pair: "BTC-TRTL", Fees: FeeSchedule{TradePercent: 0.5}},
+		{Name: "b", Pair: "BTC-TRTL", Fees: FeeSchedule{TradePercent: 0.5}},
+	}
+	assert.Len(t, FindArbitrage(markets, nil, 0), 1)
+	assert.Empty(t, FindArbitrage(markets, sources, 0))
+}
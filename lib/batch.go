@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTooManyItems is returned when a batch has more items than allowed
+var ErrTooManyItems = errors.New("batch has too many items")
+
+// BatchItem is one amount of turtle to convert, Error is set when it couldn't be read
+type BatchItem struct {
+	Label  string
+	Amount float64
+	Error  string
+}
+
+// BatchResult is a batch item converted, or why it wasn't
+type BatchResult struct {
+	Index  int      `json:"index"`
+	Label  string   `json:"label,omitempty"`
+	Amount float64  `json:"amount"`
+	Btc    *float64 `json:"btc,omitempty"`
+	Value  *float64 `json:"value,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// ParseBatchJSON reads a JSON array of amounts, each a number or an object like {"label": "alice", "amount": 1000}
+// Items that can't be read get an error instead of failing the whole batch
+func ParseBatchJSON(r io.Reader, maxItems int) (items []BatchItem, err error) {
+	var raw []json.RawMessage
+	decoder := json.NewDecoder(r)
+	if jsonErr := decoder.Decode(&raw); jsonErr != nil {
+		return items, errors.Wrap(jsonErr, "Problem reading the batch, it should be a JSON array")
+	}
+	if maxItems > 0 && len(raw) > maxItems {
+		return items, ErrTooManyItems
+	}
+
+	for _, message := range raw {
+		items = append(items, parseBatchJSONItem(message))
+	}
+	return items, nil
+}
+
+func parseBatchJSONItem(message json.RawMessage) (item BatchItem) {
+	type labelled struct {
+		Label  string          `json:"label"`
+		Amount json.RawMessage `json:"amount"`
+	}
+
+	amount := message
+	if trimmed := bytes.TrimSpace(message); len(trimmed) > 0 && trimmed[0] == '{' {
+		object := labelled{}
+		if jsonErr := json.Unmarshal(message, &object); jsonErr != nil {
+			item.Error = "item should be a number or an object with an amount"
+			return item
+		}
+		item.Label, amount = object.Label, object.Amount
+	}
+	if len(amount) == 0 {
+		item.Error = "amount is missing"
+		return item
+	}
+
+	// amounts can be quoted so big balances don't lose precision in tools that read them as floats
+	text := strings.Trim(string(bytes.TrimSpace(amount)), `"`)
+	item.Amount, item.Error = parseBatchAmount(text)
+	return item
+}
+
+// batchHeaders are the column names a batch CSV's header row can use
+var batchHeaders = map[string]bool{"label": true, "amount": true, "trtl": true}
+
+// ParseBatchCSV reads CSV rows of label,amount or just amount, skipping a header row like label,amount if there is one
+// Any other first row that can't be read is an item error, so results still line up with the rows
+func ParseBatchCSV(r io.Reader, maxItems int) (items []BatchItem, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for row := 0; ; row++ {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			return items, nil
+		}
+		if readErr != nil {
+			return items, errors.Wrap(readErr, "Problem reading the batch CSV")
+		}
+
+		item := BatchItem{}
+		switch len(record) {
+		case 1:
+			item.Amount, item.Error = parseBatchAmount(record[0])
+		case 2:
+			item.Label = record[0]
+			item.Amount, item.Error = parseBatchAmount(record[1])
+		default:
+			item.Error = fmt.Sprintf("row has %d columns, it should be label,amount or amount", len(record))
+		}
+		if row == 0 && item.Error != "" && isBatchHeader(record) {
+			continue
+		}
+
+		if maxItems > 0 && len(items) >= maxItems {
+			return items, ErrTooManyItems
+		}
+		items = append(items, item)
+	}
+}
+
+// isBatchHeader is whether every column in a row is a header name
+func isBatchHeader(record []string) bool {
+	if len(record) > 2 {
+		return false
+	}
+	for _, column := range record {
+		if !batchHeaders[strings.ToLower(strings.TrimSpace(column))] {
+			return false
+		}
+	}
+	return true
+}
+
+func parseBatchAmount(text string) (amount float64, problem string) {
+	amount, parseErr := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if parseErr != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Sprintf("%q is not an amount", text)
+	}
+	if amount < 0 {
+		return 0, "amount can't be negative"
+	}
+	return amount, ""
+}
+
+// ConvertBatch converts every item with the same price so the results are consistent with each other
+func ConvertBatch(price FiatPrice, items []BatchItem) []BatchResult {
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		results[i] = BatchResult{
+			Index:  i,
+			Label:  item.Label,
+			Amount: item.Amount,
+			Error:  item.Error,
+		}
+		if item.Error != "" {
+			continue
+		}
+		converted := ConvertTurtleFiatPrice(price, item.Amount)
+		results[i].Btc = &converted.Btc
+		results[i].Value = &converted.Value
+	}
+	return results
+}
@@ -0,0 +1,56 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBatchJSON(t *testing.T) {
+	items, err := ParseBatchJSON(strings.NewReader(`[1000, {"label": "alice", "amount": "250.5"}, {"label": "bob"}, -5, "lots", [1]]`), 10)
+	assert.Nil(t, err)
+	assert.Len(t, items, 6)
+	assert.Equal(t, BatchItem{Amount: 1000}, items[0])
+	assert.Equal(t, BatchItem{Label: "alice", Amount: 250.5}, items[1])
+	assert.Equal(t, "amount is missing", items[2].Error)
+	assert.Equal(t, "amount can't be negative", items[3].Error)
+	assert.NotEmpty(t, items[4].Error)
+	assert.NotEmpty(t, items[5].Error)
+
+	_, err = ParseBatchJSON(strings.NewReader(`[1, 2, 3]`), 2)
+	assert.Equal(t, ErrTooManyItems, err)
+	_, err = ParseBatchJSON(strings.NewReader(`{"amount": 1}`), 2)
+	assert.NotNil(t, err)
+}
+
+func TestParseBatchCSV(t *testing.T) {
+	items, err := ParseBatchCSV(strings.NewReader("label,amount\nalice,1000\nbob,abc\n42\na,b,c\n"), 10)
+	assert.Nil(t, err)
+	assert.Len(t, items, 4)
+	assert.Equal(t, BatchItem{Label: "alice", Amount: 1000}, items[0])
+	assert.NotEmpty(t, items[1].Error)
+	assert.Equal(t, BatchItem{Amount: 42}, items[2])
+	assert.NotEmpty(t, items[3].Error)
+
+	items, err = ParseBatchCSV(strings.NewReader("alice,1O00\nbob,2000\n"), 10)
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "alice", items[0].Label)
+	assert.NotEmpty(t, items[0].Error)
+	assert.Equal(t, BatchItem{Label: "bob", Amount: 2000}, items[1])
+
+	_, err = ParseBatchCSV(strings.NewReader("1\n2\n3\n"), 2)
+	assert.Equal(t, ErrTooManyItems, err)
+}
+
+func TestConvertBatch(t *testing.T) {
+	price := FiatPrice{Trtl: 1, Btc: 1e-7, Fiat: "USD", Value: 0.005}
+	results := ConvertBatch(price, []BatchItem{{Label: "alice", Amount: 1000}, {Error: "amount is missing"}})
+	assert.Equal(t, 0, results[0].Index)
+	assert.InDelta(t, 5, *results[0].Value, 1e-9)
+	assert.InDelta(t, 1e-4, *results[0].Btc, 1e-12)
+	assert.Equal(t, 1, results[1].Index)
+	assert.Nil(t, results[1].Value)
+	assert.Equal(t, "amount is missing", results[1].Error)
+}
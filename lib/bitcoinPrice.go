@@ -1,8 +1,9 @@
 package lib
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,7 +11,23 @@ import (
 
 // PullBtcToUsdPrice hits the Coinbase API to get the current Bitcoin to USD price
 func PullBtcToUsdPrice() (usdPrice float64, err error) {
-	btcToUsdURL := "https://api.coinbase.com/v2/prices/BTC-USD/spot"
+	return PullBtcToFiatPriceContext(context.Background(), "USD")
+}
+
+// PullBtcToFiatPrice hits the Coinbase API to get the current Bitcoin price in a fiat currency like EUR
+func PullBtcToFiatPrice(fiat string) (fiatPrice float64, err error) {
+	return PullBtcToFiatPriceContext(context.Background(), fiat)
+}
+
+// PullBtcToFiatPriceContext is PullBtcToFiatPrice that gives up when ctx is done
+func PullBtcToFiatPriceContext(ctx context.Context, fiat string) (fiatPrice float64, err error) {
+	return pullCoinbaseSpot(ctx, CurrentSettings().FiatURL, "coinbase", "BTC-"+fiat)
+}
+
+// pullCoinbaseSpot hits a Coinbase style API for the spot price of a pair like BTC-USD, in the second currency
+func pullCoinbaseSpot(ctx context.Context, baseURL string, source string, pair string) (spotPrice float64, err error) {
+	currentSettings := CurrentSettings()
+	spotURL := fmt.Sprintf("%s/prices/%s/spot", baseURL, pair)
 	type data struct {
 		Amount string `json:"amount"`
 	}
@@ -18,49 +35,65 @@ func PullBtcToUsdPrice() (usdPrice float64, err error) {
 		Data data `json:"data"`
 	}
 	// {"data":{"base":"BTC","currency":"USD","amount":"11110.66"}}
-	type tradeOgreResult struct {
-		// Number int `json:"number"`
-		Price string `json:"price"`
-	}
 
 	spaceClient := http.Client{
-		Timeout: time.Second * 2, // Maximum of 2 secs
+		Timeout: currentSettings.FiatTimeout,
 	}
 
-	trtlToBtcReq, reqErr := http.NewRequest(http.MethodGet, btcToUsdURL, nil)
+	spotReq, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, spotURL, nil)
 
 	if reqErr != nil {
-		return usdPrice, reqErr
-	}
-
-	res, getErr := spaceClient.Do(trtlToBtcReq)
-	if getErr != nil {
-		return usdPrice, getErr
+		return spotPrice, reqErr
 	}
 
-	body, readErr := ioutil.ReadAll(res.Body)
-	if readErr != nil {
-		return usdPrice, readErr
+	body, fetchErr := fetchUpstream(ctx, &spaceClient, source, spotReq)
+	if fetchErr != nil {
+		return spotPrice, fetchErr
 	}
 
-	tradeOgreRes := coinbaseResult{}
-	jsonErr := json.Unmarshal(body, &tradeOgreRes)
+	coinbaseRes := coinbaseResult{}
+	jsonErr := json.Unmarshal(body, &coinbaseRes)
 	if jsonErr != nil {
-		return usdPrice, jsonErr
+		return spotPrice, jsonErr
 	}
 
-	usdPrice, err = strconv.ParseFloat(tradeOgreRes.Data.Amount, 64)
-	return usdPrice, err
+	spotPrice, err = strconv.ParseFloat(coinbaseRes.Data.Amount, 64)
+	return spotPrice, err
 }
 
 // GetBtcToUsdPrice is the main bitcoin price check
-// TODO: add redis cache check here in case we got the price recently
 func GetBtcToUsdPrice(forceCheck bool) (usdPrice float64, err error) {
-	var cache float64
+	return GetBtcToFiatPriceContext(context.Background(), "USD", forceCheck)
+}
+
+// GetBtcToUsdPriceContext is GetBtcToUsdPrice that gives up when ctx is done
+func GetBtcToUsdPriceContext(ctx context.Context, forceCheck bool) (usdPrice float64, err error) {
+	return GetBtcToFiatPriceContext(ctx, "USD", forceCheck)
+}
+
+// GetBtcToFiatPrice is the bitcoin price check for any fiat currency Coinbase knows about
+// A price pulled within the fiat cache TTL is reused unless forceCheck is set
+func GetBtcToFiatPrice(fiat string, forceCheck bool) (fiatPrice float64, err error) {
+	return GetBtcToFiatPriceContext(context.Background(), fiat, forceCheck)
+}
+
+// GetBtcToFiatPriceContext is GetBtcToFiatPrice that gives up when ctx is done
+func GetBtcToFiatPriceContext(ctx context.Context, fiat string, forceCheck bool) (fiatPrice float64, err error) {
+	fiatPrice, _, err = getBtcToFiatPrice(ctx, fiat, forceCheck)
+	return fiatPrice, err
+}
 
-	if cache != 0 && !forceCheck {
-		return cache, nil
+// getBtcToFiatPrice is GetBtcToFiatPriceContext that also says when the price was pulled
+func getBtcToFiatPrice(ctx context.Context, fiat string, forceCheck bool) (fiatPrice float64, fetchedAt time.Time, err error) {
+	cacheKey := "BTC-" + fiat
+	if cached, cachedAt, ok := prices.get(cacheKey, CurrentSettings().FiatCacheTTL); ok && !forceCheck {
+		return cached, cachedAt, nil
 	}
 
-	return PullBtcToUsdPrice()
+	fetchedAt = time.Now()
+	fiatPrice, err = PullBtcToFiatPriceContext(ctx, fiat)
+	if err == nil {
+		prices.set(cacheKey, fiatPrice, fetchedAt)
+	}
+	return fiatPrice, fetchedAt, err
 }
@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	value     float64
+	fetchedAt time.Time
+}
+
+type tickerEntry struct {
+	ticker    tradeOgreTicker
+	fetchedAt time.Time
+}
+
+// priceCache remembers recently pulled prices so every request doesn't hit the upstream APIs
+// Whole tickers are kept too, for the markets that need more than the last price, and averaged TRTL-BTC quotes with
+// the sources they came from
+type priceCache struct {
+	lock    sync.Mutex
+	entries map[string]cacheEntry
+	tickers map[string]tickerEntry
+	quotes  map[string]btcQuote
+}
+
+var prices = &priceCache{entries: map[string]cacheEntry{}, tickers: map[string]tickerEntry{}, quotes: map[string]btcQuote{}}
+
+// get is a price pulled within ttl and when it was pulled
+func (cache *priceCache) get(key string, ttl time.Duration) (float64, time.Time, bool) {
+	if ttl <= 0 {
+		return 0, time.Time{}, false
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	entry, ok := cache.entries[key]
+	if !ok || time.Since(entry.fetchedAt) > ttl {
+		return 0, time.Time{}, false
+	}
+	return entry.value, entry.fetchedAt, true
+}
+
+func (cache *priceCache) set(key string, value float64, fetchedAt time.Time) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.entries[key] = cacheEntry{value: value, fetchedAt: fetchedAt}
+}
+
+// getTicker is a ticker pulled within ttl and when it was pulled
+func (cache *priceCache) getTicker(key string, ttl time.Duration) (tradeOgreTicker, time.Time, bool) {
+	if ttl <= 0 {
+		return tradeOgreTicker{}, time.Time{}, false
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	entry, ok := cache.tickers[key]
+	if !ok || time.Since(entry.fetchedAt) > ttl {
+		return tradeOgreTicker{}, time.Time{}, false
+	}
+	return entry.ticker, entry.fetchedAt, true
+}
+
+func (cache *priceCache) setTicker(key string, ticker tradeOgreTicker, fetchedAt time.Time) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.tickers[key] = tickerEntry{ticker: ticker, fetchedAt: fetchedAt}
+}
+
+// getQuote is a quote pulled within ttl
+func (cache *priceCache) getQuote(key string, ttl time.Duration) (btcQuote, bool) {
+	if ttl <= 0 {
+		return btcQuote{}, false
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	quote, ok := cache.quotes[key]
+	if !ok || time.Since(quote.fetchedAt) > ttl {
+		return btcQuote{}, false
+	}
+	return quote, true
+}
+
+// lastQuote is the quote most recently pulled for key however old it is, for when a fresh one can't be had
+func (cache *priceCache) lastQuote(key string) (btcQuote, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	quote, ok := cache.quotes[key]
+	return quote, ok
+}
+
+func (cache *priceCache) setQuote(key string, quote btcQuote) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.quotes[key] = quote
+}
+
+func (cache *priceCache) clear() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.entries = map[string]cacheEntry{}
+	cache.tickers = map[string]tickerEntry{}
+	cache.quotes = map[string]btcQuote{}
+}
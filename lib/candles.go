@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"time"
+)
+
+// PriceSample is TRTL's price in one currency at a point in time
+// Volume is the BTC traded over the 24 hours before it, zero when it wasn't recorded
+type PriceSample struct {
+	Time   time.Time `json:"time"`
+	Price  float64   `json:"price"`
+	Volume float64   `json:"volume,omitempty"`
+}
+
+// Candle is the open, high, low and close price over one period starting at Time
+// Samples is how many recorded prices it was made from, Volume the mean 24 hour BTC volume of the ones that had one
+type Candle struct {
+	Time    time.Time `json:"time"`
+	Open    float64   `json:"open"`
+	High    float64   `json:"high"`
+	Low     float64   `json:"low"`
+	Close   float64   `json:"close"`
+	Volume  float64   `json:"volume,omitempty"`
+	Samples int       `json:"samples"`
+}
+
+// Samples picks the prices in currency (BTC or a fiat) out of points, skipping points without that fiat
+func Samples(points []PricePoint, currency string) []PriceSample {
+	var samples []PriceSample
+	for _, point := range points {
+		if price, ok := point.Price(currency); ok {
+			samples = append(samples, PriceSample{Time: point.Time, Price: price, Volume: point.Volume})
+		}
+	}
+	return samples
+}
+
+// Candles groups samples in time order into candles of the given period, aligned to from
+// Periods without any samples are left out rather than drawn flat
+func Candles(samples []PriceSample, from time.Time, period time.Duration) []Candle {
+	var candles []Candle
+	withVolume := 0
+	for _, sample := range samples {
+		if sample.Time.Before(from) {
+			continue
+		}
+		start := from.Add(sample.Time.Sub(from) / period * period)
+		if len(candles) == 0 || !candles[len(candles)-1].Time.Equal(start) {
+			candles = append(candles, Candle{
+				Time:  start,
+				Open:  sample.Price,
+				High:  sample.Price,
+				Low:   sample.Price,
+				Close: sample.Price,
+			})
+			withVolume = 0
+		}
+
+		candle := &candles[len(candles)-1]
+		if sample.Price > candle.High {
+			candle.High = sample.Price
+		}
+		if sample.Price < candle.Low {
+			candle.Low = sample.Price
+		}
+		candle.Close = sample.Price
+		candle.Samples++
+		if sample.Volume > 0 {
+			// a running mean, so the volume doesn't grow with how often the price was recorded
+			withVolume++
+			candle.Volume += (sample.Volume - candle.Volume) / float64(withVolume)
+		}
+	}
+	return candles
+}
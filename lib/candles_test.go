@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCandles(t *testing.T) {
+	from := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	points := []PricePoint{
+		{Time: from.Add(10 * time.Minute), TrtlToBtc: 2e-7, BtcToFiat: map[string]float64{"USD": 10000}},
+		{Time: from.Add(20 * time.Minute), TrtlToBtc: 4e-7, BtcToFiat: map[string]float64{"EUR": 8000}},
+		{Time: from.Add(30 * time.Minute), TrtlToBtc: 1e-7, BtcToFiat: map[string]float64{"USD": 10000}},
+		{Time: from.Add(50 * time.Minute), TrtlToBtc: 3e-7, BtcToFiat: map[string]float64{"USD": 10000}},
+		{Time: from.Add(3 * time.Hour), TrtlToBtc: 5e-7, BtcToFiat: map[string]float64{"USD": 10000}},
+	}
+
+	samples := Samples(points, "USD")
+	assert.Len(t, samples, 4)
+	assert.Len(t, Samples(points, "BTC"), 5)
+
+	candles := Candles(samples, from, time.Hour)
+	assert.Len(t, candles, 2)
+	assert.Equal(t, from, candles[0].Time)
+	assert.InDelta(t, 2e-3, candles[0].Open, 1e-12)
+	assert.InDelta(t, 3e-3, candles[0].High, 1e-12)
+	assert.InDelta(t, 1e-3, candles[0].Low, 1e-12)
+	assert.InDelta(t, 3e-3, candles[0].Close, 1e-12)
+	assert.Equal(t, 3, candles[0].Samples)
+	assert.Equal(t, from.Add(3*time.Hour), candles[1].Time)
+	assert.Equal(t, 1, candles[1].Samples)
+}
+
+func TestCandleVolumeIsTheMeanOfTheRecordedVolumes(t *testing.T) {
+	from := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	samples := []PriceSample{
+		{Time: from.Add(10 * time.Minute), Price: 1, Volume: 2},
+		{Time: from.Add(20 * time.Minute), Price: 1},
+		{Time: from.Add(30 * time.Minute), Price: 1, Volume: 4},
+		{Time: from.Add(2 * time.Hour), Price: 1},
+	}
+
+	candles := Candles(samples, from, time.Hour)
+	assert.Len(t, candles, 2)
+	assert.Equal(t, 3.0, candles[0].Volume)
+	assert.Equal(t, 0.0, candles[1].Volume)
+}
@@ -0,0 +1,26 @@
+package lib
+
+import (
+	"context"
+	"time"
+)
+
+// budget gives the next upstream call share of whatever time is left before ctx's deadline
+// so a slow first leg can't use up the time the second leg needs
+func budget(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(float64(time.Until(deadline))*share))
+}
+
+// exchangeShare is the part of a deadline the exchange leg gets, in proportion to the configured timeouts
+func exchangeShare() float64 {
+	currentSettings := CurrentSettings()
+	total := currentSettings.ExchangeTimeout + currentSettings.FiatTimeout
+	if total <= 0 {
+		return 0.5
+	}
+	return float64(currentSettings.ExchangeTimeout) / float64(total)
+}
@@ -0,0 +1,43 @@
+package lib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetSplitsTheDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	legCtx, legCancel := budget(ctx, 0.6)
+	defer legCancel()
+	deadline, ok := legCtx.Deadline()
+	assert.True(t, ok)
+	assert.InDelta(t, 6*time.Second, time.Until(deadline), float64(100*time.Millisecond))
+
+	noDeadlineCtx, noDeadlineCancel := budget(context.Background(), 0.6)
+	defer noDeadlineCancel()
+	_, ok = noDeadlineCtx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestPullSourcePriceStopsWhenCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := PullSourcePriceContext(ctx, Source{Name: "slow", Kind: "tradeogre", URL: server.URL, Pair: "BTC-TRTL", Weight: 1})
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(started) < time.Second)
+}
@@ -1,24 +1,42 @@
 package lib
 
 import (
+	"context"
+	"time"
+
 	"github.com/pkg/errors"
 )
 
 // GetPriceHash is the main driver that will get both the BTC and USD prices
 func GetPriceHash(forceCheck bool) (price CurrentPrice, err error) {
-	currentTrtlBtcPrice, getBtcPriceErr := GetTrtlToBtcPrice(forceCheck)
+	return GetPriceHashContext(context.Background(), forceCheck)
+}
+
+// GetPriceHashContext is GetPriceHash that gives up when ctx is done
+// If ctx has a deadline the TRTL leg only gets its share of it so the USD leg still has time
+func GetPriceHashContext(ctx context.Context, forceCheck bool) (price CurrentPrice, err error) {
+	btcCtx, cancel := budget(ctx, exchangeShare())
+	btc, getBtcPriceErr := getTrtlToBtcQuote(btcCtx, forceCheck)
+	cancel()
 
 	if getBtcPriceErr != nil {
 		return price, errors.Wrap(getBtcPriceErr, "Problem getting BTC Price")
 	}
+	currentTrtlBtcPrice := btc.price
 	price.btcPrice = currentTrtlBtcPrice
+	price.sources = btc.sources
+	price.stale = btc.stale
 
-	currentUsdBtcPrice, getUsdBtcErr := GetBtcToUsdPrice(forceCheck)
+	currentUsdBtcPrice, usdFetchedAt, getUsdBtcErr := getBtcToFiatPrice(ctx, "USD", forceCheck)
 
 	if getUsdBtcErr != nil {
 		return price, getUsdBtcErr
 	}
+	price.fetchedAt = olderTime(btc.fetchedAt, usdFetchedAt)
 
+	if !btc.stale {
+		recordPrice(ctx, btc, "USD", currentUsdBtcPrice)
+	}
 	trtlToUsd := currentTrtlBtcPrice * currentUsdBtcPrice
 
 	price.usdPrice = trtlToUsd
@@ -28,21 +46,66 @@ func GetPriceHash(forceCheck bool) (price CurrentPrice, err error) {
 }
 
 // GetTrtlToBtcPrice is the main turtle to bitcoin price check
-// TODO: add redis cache check here in case we got the price recently
+// A price pulled within the TRTL cache TTL is reused unless forceCheck is set
 func GetTrtlToBtcPrice(forceCheck bool) (btcPrice float64, err error) {
-	// TODO: add redis cache check here in case we got the price recently
-	var cache float64
+	return GetTrtlToBtcPriceContext(context.Background(), forceCheck)
+}
+
+// GetTrtlToBtcPriceContext is GetTrtlToBtcPrice that gives up when ctx is done
+func GetTrtlToBtcPriceContext(ctx context.Context, forceCheck bool) (btcPrice float64, err error) {
+	quote, err := getTrtlToBtcQuote(ctx, forceCheck)
+	return quote.price, err
+}
+
+// btcQuote is a TRTL-BTC price, when it was pulled, the sources that were averaged for it and the BTC traded on
+// them over the last 24 hours
+// stale is set when every fresh quote was quarantined and it's the last accepted price instead
+type btcQuote struct {
+	price     float64
+	fetchedAt time.Time
+	sources   []string
+	volume    float64
+	stale     bool
+}
+
+// getTrtlToBtcQuote is GetTrtlToBtcPriceContext that also says when the price was pulled, where from and whether it's
+// stale
+func getTrtlToBtcQuote(ctx context.Context, forceCheck bool) (quote btcQuote, err error) {
+	if cached, ok := prices.getQuote("BTC-TRTL", CurrentSettings().TrtlCacheTTL); ok && !forceCheck {
+		return cached, nil
+	}
 
-	if cache != 0 && !forceCheck {
-		return cache, nil
+	quote, err = pullTrtlToBtcQuote(ctx)
+	if err == ErrQuotesQuarantined {
+		// the last price that got through is better than none, it keeps when it was fetched so it shows as stale
+		if last, ok := prices.lastQuote("BTC-TRTL"); ok {
+			Logger(ctx).Warn("Every quote was quarantined, serving the last accepted price", "price", last.price, "fetchedAt", last.fetchedAt)
+			last.stale = true
+			return last, nil
+		}
+	}
+	if err == nil {
+		prices.setQuote("BTC-TRTL", quote)
 	}
+	return quote, err
+}
 
-	return PullTrtlToBtcPrice()
+// olderTime is whichever of two times is earlier, how fresh a price built from two legs is
+func olderTime(a time.Time, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
 }
 
 // ConvertTurtle does the math for converting turtle coins into BTC and USD
 func ConvertTurtle(trtl int64, forceCheck bool) (priceHash CurrentPrice, err error) {
-	currentPrice, getCurrentPriceError := GetPriceHash(forceCheck)
+	return ConvertTurtleContext(context.Background(), trtl, forceCheck)
+}
+
+// ConvertTurtleContext is ConvertTurtle that gives up when ctx is done
+func ConvertTurtleContext(ctx context.Context, trtl int64, forceCheck bool) (priceHash CurrentPrice, err error) {
+	currentPrice, getCurrentPriceError := GetPriceHashContext(ctx, forceCheck)
 	if getCurrentPriceError != nil {
 		return priceHash, errors.Wrap(getCurrentPriceError, "Problem getting the current price")
 	}
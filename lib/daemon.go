@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoDaemon is returned when no daemon URL is configured
+var ErrNoDaemon = errors.New("no TurtleCoin daemon is configured")
+
+// DaemonInfo is the part of a daemon's /getinfo we care about
+type DaemonInfo struct {
+	Height     uint64 `json:"height"`
+	Difficulty uint64 `json:"difficulty"`
+	Hashrate   uint64 `json:"hashrate"`
+}
+
+// BlockHeader is a block header from the daemon's json_rpc API
+type BlockHeader struct {
+	Height     uint64 `json:"height"`
+	Hash       string `json:"hash"`
+	Reward     uint64 `json:"reward"`
+	Difficulty uint64 `json:"difficulty"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// DaemonClient talks to a TurtleCoind node's RPC interface
+type DaemonClient struct {
+	URL    string
+	client http.Client
+}
+
+// NewDaemonClient makes a client for the daemon at url, like http://localhost:11898
+func NewDaemonClient(url string) *DaemonClient {
+	return &DaemonClient{
+		URL: strings.TrimRight(url, "/"),
+		client: http.Client{
+			Timeout: CurrentSettings().DaemonTimeout,
+		},
+	}
+}
+
+// GetDaemon returns a client for the configured daemon
+func GetDaemon() (*DaemonClient, error) {
+	url := CurrentSettings().DaemonURL
+	if url == "" {
+		return nil, ErrNoDaemon
+	}
+	return NewDaemonClient(url), nil
+}
+
+// GetInfo hits /getinfo for the height and network difficulty
+func (daemon *DaemonClient) GetInfo() (info DaemonInfo, err error) {
+	return daemon.GetInfoContext(context.Background())
+}
+
+// GetInfoContext is GetInfo that gives up when ctx is done
+func (daemon *DaemonClient) GetInfoContext(ctx context.Context) (info DaemonInfo, err error) {
+	infoReq, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, daemon.URL+"/getinfo", nil)
+	if reqErr != nil {
+		return info, reqErr
+	}
+
+	body, fetchErr := fetchUpstream(ctx, &daemon.client, "daemon", infoReq)
+	if fetchErr != nil {
+		return info, fetchErr
+	}
+
+	jsonErr := json.Unmarshal(body, &info)
+	return info, jsonErr
+}
+
+// GetLastBlockHeader asks the daemon for the header of the top block
+func (daemon *DaemonClient) GetLastBlockHeader() (header BlockHeader, err error) {
+	return daemon.GetLastBlockHeaderContext(context.Background())
+}
+
+// GetLastBlockHeaderContext is GetLastBlockHeader that gives up when ctx is done
+func (daemon *DaemonClient) GetLastBlockHeaderContext(ctx context.Context) (header BlockHeader, err error) {
+	type lastBlockHeaderResult struct {
+		BlockHeader BlockHeader `json:"block_header"`
+	}
+	result := lastBlockHeaderResult{}
+	err = daemon.jsonRPC(ctx, "getlastblockheader", struct{}{}, &result)
+	return result.BlockHeader, err
+}
+
+// GetAlreadyGeneratedCoins asks the daemon how many atomic units exist as of the top block
+func (daemon *DaemonClient) GetAlreadyGeneratedCoins() (height uint64, coins uint64, err error) {
+	return daemon.GetAlreadyGeneratedCoinsContext(context.Background())
+}
+
+// GetAlreadyGeneratedCoinsContext is GetAlreadyGeneratedCoins that gives up when ctx is done
+func (daemon *DaemonClient) GetAlreadyGeneratedCoinsContext(ctx context.Context) (height uint64, coins uint64, err error) {
+	header, headerErr := daemon.GetLastBlockHeaderContext(ctx)
+	if headerErr != nil {
+		return height, coins, headerErr
+	}
+
+	type block struct {
+		AlreadyGeneratedCoins json.RawMessage `json:"alreadyGeneratedCoins"`
+	}
+	type blockResult struct {
+		Block block `json:"block"`
+	}
+	result := blockResult{}
+	rpcErr := daemon.jsonRPC(ctx, "f_block_json", map[string]string{"hash": header.Hash}, &result)
+	if rpcErr != nil {
+		return height, coins, rpcErr
+	}
+
+	// older daemons send this as a number, newer ones as a string
+	raw := strings.Trim(string(result.Block.AlreadyGeneratedCoins), `"`)
+	coins, err = strconv.ParseUint(raw, 10, 64)
+	return header.Height, coins, err
+}
+
+func (daemon *DaemonClient) jsonRPC(ctx context.Context, method string, params interface{}, result interface{}) error {
+	type rpcError struct {
+		Message string `json:"message"`
+	}
+	type rpcResponse struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+
+	payload, jsonErr := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      "turtle-utils",
+		"method":  method,
+		"params":  params,
+	})
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	rpcReq, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, daemon.URL+"/json_rpc", bytes.NewReader(payload))
+	if reqErr != nil {
+		return reqErr
+	}
+	rpcReq.Header.Set("Content-Type", "application/json")
+
+	body, fetchErr := fetchUpstream(ctx, &daemon.client, "daemon", rpcReq)
+	if fetchErr != nil {
+		return fetchErr
+	}
+
+	response := rpcResponse{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return errors.Errorf("%s: %s", method, response.Error.Message)
+	}
+	return json.Unmarshal(response.Result, result)
+}
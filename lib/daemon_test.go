@@ -0,0 +1,33 @@
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemonGetAlreadyGeneratedCoins(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := struct {
+			Method string `json:"method"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&request)
+		switch request.Method {
+		case "getlastblockheader":
+			w.Write([]byte(`{"jsonrpc":"2.0","result":{"block_header":{"height":400000,"hash":"abc","reward":2945000}}}`))
+		case "f_block_json":
+			w.Write([]byte(`{"jsonrpc":"2.0","result":{"block":{"alreadyGeneratedCoins":"1189765433210"}}}`))
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","error":{"message":"Method not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	height, coins, err := NewDaemonClient(server.URL + "/").GetAlreadyGeneratedCoins()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(400000), height)
+	assert.Equal(t, uint64(1189765433210), coins)
+}
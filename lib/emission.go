@@ -0,0 +1,143 @@
+package lib
+
+import (
+	"sync"
+	"time"
+)
+
+// TurtleCoin's emission parameters from CryptoNoteConfig.h
+const (
+	// MoneySupply is the most atomic units that will ever exist (1 trillion TRTL)
+	MoneySupply uint64 = 100000000000000
+	// EmissionSpeedFactor is how far the unmined supply is shifted to get each block reward
+	EmissionSpeedFactor uint = 25
+	// DifficultyTarget is the block target time
+	DifficultyTarget = 30 * time.Second
+	// AtomicUnits is how many atomic units make one TRTL (2 decimal places)
+	AtomicUnits = 100
+	// BlocksPerYear is how many blocks are mined in a year at the target time
+	BlocksPerYear uint64 = 365.25 * 24 * 60 * 60 / 30
+)
+
+// GenesisTime is when TurtleCoin's genesis block was mined
+var GenesisTime = time.Unix(1512800692, 0).UTC()
+
+// BlockReward is the base reward for the next block given how many atomic units have been generated so far
+func BlockReward(alreadyGeneratedCoins uint64) uint64 {
+	if alreadyGeneratedCoins >= MoneySupply {
+		return 0
+	}
+	return (MoneySupply - alreadyGeneratedCoins) >> EmissionSpeedFactor
+}
+
+var (
+	emissionCheckpointLock   sync.Mutex
+	emissionCheckpointHeight uint64
+	emissionCheckpointCoins  = BlockReward(0)
+)
+
+// GeneratedCoins is the atomic units in existence once the block at height has been mined (genesis is height 0)
+// The chain is walked block by block, so the furthest height asked for up to the chain's current height is kept to
+// start from next time; projections further out don't move it or hold the lock while they walk
+func GeneratedCoins(height uint64) uint64 {
+	emissionCheckpointLock.Lock()
+	fromHeight, coins := emissionCheckpointHeight, emissionCheckpointCoins
+	emissionCheckpointLock.Unlock()
+
+	if height < fromHeight {
+		fromHeight, coins = 0, BlockReward(0)
+	}
+	coins = walkEmission(fromHeight, coins, height)
+
+	if height <= EstimateHeight(time.Now()) {
+		emissionCheckpointLock.Lock()
+		if height > emissionCheckpointHeight {
+			emissionCheckpointHeight, emissionCheckpointCoins = height, coins
+		}
+		emissionCheckpointLock.Unlock()
+	}
+	return coins
+}
+
+func walkEmission(fromHeight uint64, coins uint64, toHeight uint64) uint64 {
+	for h := fromHeight; h < toHeight; h++ {
+		coins += BlockReward(coins)
+	}
+	return coins
+}
+
+// EstimateHeight guesses the chain height at a time assuming every block hit the target time
+func EstimateHeight(at time.Time) uint64 {
+	if at.Before(GenesisTime) {
+		return 0
+	}
+	return uint64(at.Sub(GenesisTime) / DifficultyTarget)
+}
+
+// AtomicToTrtl converts atomic units into TRTL
+func AtomicToTrtl(atomic uint64) float64 {
+	return float64(atomic) / AtomicUnits
+}
+
+// Emission is the state of the emission curve at one block
+type Emission struct {
+	Height           uint64    `json:"height"`
+	EstimatedTime    time.Time `json:"estimatedTime"`
+	Reward           float64   `json:"reward"`
+	Supply           float64   `json:"supply"`
+	SupplyPercent    float64   `json:"supplyPercent"`
+	InflationPercent float64   `json:"inflationPercent"`
+}
+
+// EstimateTime guesses when a height will be (or was) mined assuming every block hit the target time
+func EstimateTime(height uint64) time.Time {
+	return GenesisTime.Add(time.Duration(height) * DifficultyTarget)
+}
+
+// BlockRewardAt is the base reward paid by the block at height, in atomic units
+func BlockRewardAt(height uint64) uint64 {
+	if height == 0 {
+		return BlockReward(0)
+	}
+	return BlockReward(GeneratedCoins(height - 1))
+}
+
+// EmissionAt works out the reward, supply and yearly inflation at a height without a node
+func EmissionAt(height uint64) Emission {
+	supply := GeneratedCoins(height)
+	return newEmission(height, BlockRewardAt(height), supply)
+}
+
+// EmissionProjection walks the curve from fromHeight to toHeight, giving a point every step blocks plus toHeight itself
+func EmissionProjection(fromHeight uint64, toHeight uint64, step uint64) (projection []Emission) {
+	if step == 0 || toHeight < fromHeight {
+		return projection
+	}
+
+	var supply uint64
+	if fromHeight > 0 {
+		supply = GeneratedCoins(fromHeight - 1)
+	}
+	for height := fromHeight; height <= toHeight; height++ {
+		reward := BlockReward(supply)
+		supply += reward
+		if (height-fromHeight)%step == 0 || height == toHeight {
+			projection = append(projection, newEmission(height, reward, supply))
+		}
+	}
+	return projection
+}
+
+func newEmission(height uint64, reward uint64, supply uint64) Emission {
+	emission := Emission{
+		Height:        height,
+		EstimatedTime: EstimateTime(height),
+		Reward:        AtomicToTrtl(reward),
+		Supply:        AtomicToTrtl(supply),
+		SupplyPercent: float64(supply) / float64(MoneySupply) * 100,
+	}
+	if supply > 0 {
+		emission.InflationPercent = float64(reward*BlocksPerYear) / float64(supply) * 100
+	}
+	return emission
+}
@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// genesisCoinbaseTx is GENESIS_COINBASE_TX_HEX from TurtleCoin's CryptoNoteConfig.h, the transaction that paid the
+// genesis block's reward
+const genesisCoinbaseTx = "010a01ff000188f3b501029b2e4c0281c0b02e7c53291a94d1d0cbff8883f8024f5142ee494ffbbd088071210142694232c5b04151d9e4c27d31ec7a68ea568b19488cfcb422659a07a0e44dd5"
+
+// genesisReward reads the amount of the coinbase transaction's only output: version, unlock time, one input (the
+// 0xff tag and the height), one output and then its amount, all varints
+func genesisReward(t *testing.T) uint64 {
+	tx, err := hex.DecodeString(genesisCoinbaseTx)
+	assert.Nil(t, err)
+	varint := func() (value uint64) {
+		for shift := uint(0); ; shift += 7 {
+			b := tx[0]
+			tx = tx[1:]
+			value |= uint64(b&0x7f) << shift
+			if b < 0x80 {
+				return value
+			}
+		}
+	}
+	varint() // version
+	varint() // unlock time
+	assert.Equal(t, uint64(1), varint(), "inputs")
+	assert.Equal(t, byte(0xff), tx[0], "coinbase input")
+	tx = tx[1:]
+	assert.Equal(t, uint64(0), varint(), "height")
+	assert.Equal(t, uint64(1), varint(), "outputs")
+	return varint()
+}
+
+func TestEmissionAtKnownHeights(t *testing.T) {
+	// TurtleCoin launched paying 29,802.32 TRTL a block
+	reward := genesisReward(t)
+	assert.Equal(t, uint64(2980232), reward)
+	assert.Equal(t, reward, BlockRewardAt(0))
+	assert.Equal(t, reward, GeneratedCoins(0))
+	genesis := EmissionAt(0)
+	assert.Equal(t, 29802.32, genesis.Reward)
+	assert.Equal(t, 29802.32, genesis.Supply)
+	assert.Equal(t, GenesisTime, genesis.EstimatedTime)
+
+	// base reward and already generated coins after each block, in atomic units, pinned exactly so a change to the
+	// rounding shows up; these are off the curve, only genesis above is checked against the chain itself
+	for _, known := range []struct {
+		height uint64
+		reward uint64
+		supply uint64
+	}{
+		{1, 2980232, 5960464},
+		{100000, 2971363, 297582501360},
+		{500000, 2936152, 1479071539539},
+		{1000000, 2892724, 2936263656485},
+		{2000000, 2807786, 5786308048186},
+	} {
+		assert.Equal(t, known.reward, BlockRewardAt(known.height), "reward at %d", known.height)
+		assert.Equal(t, known.supply, GeneratedCoins(known.height), "supply at %d", known.height)
+	}
+
+	// walking backwards after the checkpoint has moved on should give the same answer
+	atHundredThousand := GeneratedCoins(100000)
+	GeneratedCoins(2500000)
+	assert.Equal(t, atHundredThousand, GeneratedCoins(100000))
+}
+
+func TestProjectionsDontMoveTheEmissionCheckpoint(t *testing.T) {
+	current := EstimateHeight(time.Now())
+	GeneratedCoins(current)
+	emissionCheckpointLock.Lock()
+	checkpoint := emissionCheckpointHeight
+	emissionCheckpointLock.Unlock()
+	assert.Equal(t, current, checkpoint)
+
+	GeneratedCoins(current + BlocksPerYear)
+	emissionCheckpointLock.Lock()
+	defer emissionCheckpointLock.Unlock()
+	assert.Equal(t, checkpoint, emissionCheckpointHeight)
+}
+
+func TestEmissionProjection(t *testing.T) {
+	projection := EmissionProjection(0, 1000000, 500000)
+	assert.Len(t, projection, 3)
+	assert.Equal(t, EmissionAt(500000), projection[1])
+	assert.Equal(t, EmissionAt(1000000), projection[2])
+	assert.InDelta(t, projection[2].Reward*float64(BlocksPerYear)/projection[2].Supply*100, projection[2].InflationPercent, 1e-9)
+
+	projection = EmissionProjection(100000, 100010, 3)
+	assert.Len(t, projection, 5)
+	assert.Equal(t, EmissionAt(100010), projection[4])
+}
+
+func TestEstimateHeight(t *testing.T) {
+	assert.Equal(t, uint64(0), EstimateHeight(GenesisTime.Add(-time.Hour)))
+	assert.Equal(t, uint64(2880), EstimateHeight(GenesisTime.Add(24*time.Hour)))
+	assert.Equal(t, GenesisTime.Add(24*time.Hour), EstimateTime(2880))
+}
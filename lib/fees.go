@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Net conversion modes, what the TRTL is assumed to sell at
+const (
+	NetModeSpot      = "spot"
+	NetModeOrderBook = "orderbook"
+)
+
+// ErrBookTooThin is returned when the order book can't take everything being sold
+var ErrBookTooThin = errors.New("the order book can't take that much")
+
+// FeeSchedule is what a source charges
+type FeeSchedule struct {
+	TradePercent float64 // of each trade
+	Withdrawal   float64 // in the market currency, BTC for BTC-TRTL
+}
+
+// FeeLine is one cost of selling, in the currency it's charged in and valued in fiat
+// Slippage can be negative when the book fills better than the last trade
+type FeeLine struct {
+	Name     string  `json:"name"`
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+	Value    float64 `json:"value"`
+}
+
+// NetProceeds is what selling TRTL on a source and withdrawing the BTC would actually leave
+// Gross less every fee line is Net, which is negative when the fees cost more than the sale
+type NetProceeds struct {
+	Trtl      float64   `json:"trtl"`
+	Source    string    `json:"source"`
+	Mode      string    `json:"mode"`
+	Fiat      string    `json:"fiat"`
+	SpotPrice float64   `json:"spotPrice"`
+	Gross     float64   `json:"gross"`
+	Fees      []FeeLine `json:"fees"`
+	TotalFees float64   `json:"totalFees"`
+	Net       float64   `json:"net"`
+	NetBtc    float64   `json:"netBtc"`
+}
+
+// ConvertTurtleNet works out the net fiat from selling trtl on a BTC-TRTL source, the first one when sourceName is empty
+// The network fee comes off before the sale, the trade and withdrawal fees after it
+func ConvertTurtleNet(trtl float64, fiat string, mode string, sourceName string, forceCheck bool) (net NetProceeds, err error) {
+	return ConvertTurtleNetContext(context.Background(), trtl, fiat, mode, sourceName, forceCheck)
+}
+
+// ConvertTurtleNetContext is ConvertTurtleNet that gives up when ctx is done
+func ConvertTurtleNetContext(ctx context.Context, trtl float64, fiat string, mode string, sourceName string, forceCheck bool) (net NetProceeds, err error) {
+	if mode != NetModeSpot && mode != NetModeOrderBook {
+		return net, errors.Errorf("mode must be %s or %s, not %s", NetModeSpot, NetModeOrderBook, mode)
+	}
+	source, sourceErr := BtcSource(sourceName)
+	if sourceErr != nil {
+		return net, sourceErr
+	}
+	networkFee := CurrentSettings().NetworkFee
+	if trtl <= networkFee {
+		return net, errors.Errorf("%s TRTL doesn't cover the %s TRTL network fee",
+			strconv.FormatFloat(trtl, 'f', -1, 64), strconv.FormatFloat(networkFee, 'f', -1, 64))
+	}
+
+	// the fees and the book are the source's, so the price is too rather than the average of every source
+	spot, priceErr := getSourcePrice(ctx, source, forceCheck)
+	if priceErr != nil {
+		return net, errors.Wrapf(priceErr, "Problem getting %s's price", source.Name)
+	}
+	// a fat-finger print isn't what selling would get, so the price is screened like the published one
+	if len(checkQuotes([]sourceQuote{{source: source, price: spot}}, recentBtcSamples(CurrentSettings().Anomaly))) == 0 {
+		return net, errors.Wrapf(ErrQuotesQuarantined, "%s's last trade of %s BTC", source.Name, strconv.FormatFloat(spot, 'f', -1, 64))
+	}
+
+	soldBtc := (trtl - networkFee) * spot
+	if mode == NetModeOrderBook {
+		book, bookErr := pullTradeOgreOrderBook(ctx, source)
+		if bookErr != nil {
+			return net, errors.Wrap(bookErr, "Problem getting the order book")
+		}
+		fill, fillErr := book.Fill("sell", trtl-networkFee, spot)
+		if fillErr != nil {
+			return net, fillErr
+		}
+		if fill.Unfilled > 0 {
+			return net, errors.Wrapf(ErrBookTooThin, "%s can only take %s of %s TRTL", source.Name,
+				strconv.FormatFloat(fill.Filled, 'f', -1, 64), strconv.FormatFloat(fill.Requested, 'f', -1, 64))
+		}
+		soldBtc = fill.BtcTotal
+	}
+
+	fiat = strings.ToUpper(fiat)
+	btcToFiat, fiatErr := GetBtcToFiatPriceContext(ctx, fiat, forceCheck)
+	if fiatErr != nil {
+		return net, errors.Wrapf(fiatErr, "Problem getting BTC to %s price", fiat)
+	}
+
+	net = netProceeds(trtl, spot, soldBtc, btcToFiat, source.Fees, networkFee, mode == NetModeOrderBook)
+	net.Source, net.Mode, net.Fiat = source.Name, mode, fiat
+	return net, nil
+}
+
+// BtcSource is the BTC-TRTL source called name, or the first one when name is empty
+func BtcSource(name string) (source Source, err error) {
+	for _, btcSource := range btcSources() {
+		if name == "" || btcSource.Name == name {
+			return btcSource, nil
+		}
+	}
+	if name == "" {
+		return source, errors.New("no tradeogre BTC-TRTL source is configured")
+	}
+	return source, errors.Errorf("%s is not a configured BTC-TRTL source", name)
+}
+
+// netProceeds takes the fee lines off trtl valued at spot, soldBtc is what the sale after the network fee gave
+func netProceeds(trtl float64, spot float64, soldBtc float64, btcToFiat float64, schedule FeeSchedule, networkFee float64, slippage bool) (net NetProceeds) {
+	net = NetProceeds{
+		Trtl:      trtl,
+		SpotPrice: spot,
+		Gross:     trtl * spot * btcToFiat,
+		Fees: []FeeLine{
+			{Name: "network", Currency: "TRTL", Amount: networkFee, Value: networkFee * spot * btcToFiat},
+		},
+	}
+	if slippage {
+		slipped := (trtl-networkFee)*spot - soldBtc
+		net.Fees = append(net.Fees, FeeLine{Name: "slippage", Currency: "BTC", Amount: slipped, Value: slipped * btcToFiat})
+	}
+	tradeFee := soldBtc * schedule.TradePercent / 100
+	net.Fees = append(net.Fees,
+		FeeLine{Name: "trade", Currency: "BTC", Amount: tradeFee, Value: tradeFee * btcToFiat},
+		FeeLine{Name: "withdrawal", Currency: "BTC", Amount: schedule.Withdrawal, Value: schedule.Withdrawal * btcToFiat},
+	)
+
+	for _, line := range net.Fees {
+		net.TotalFees += line.Value
+	}
+	net.NetBtc = soldBtc - tradeFee - schedule.Withdrawal
+	net.Net = net.NetBtc * btcToFiat
+	return net
+}
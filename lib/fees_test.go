@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetProceedsSpot(t *testing.T) {
+	// 100,000 TRTL at 10 sats with BTC at 10,000: 100 gross
+	net := netProceeds(100000, 0.0000001, (100000-10)*0.0000001, 10000, FeeSchedule{TradePercent: 0.2, Withdrawal: 0.0005}, 10, false)
+	assert.InDelta(t, 100.0, net.Gross, 1e-9)
+	assert.Len(t, net.Fees, 3)
+	assert.Equal(t, "network", net.Fees[0].Name)
+	assert.InDelta(t, 0.01, net.Fees[0].Value, 1e-9)
+	assert.Equal(t, "trade", net.Fees[1].Name)
+	assert.InDelta(t, 0.19998, net.Fees[1].Value, 1e-9)
+	assert.Equal(t, "withdrawal", net.Fees[2].Name)
+	assert.InDelta(t, 5.0, net.Fees[2].Value, 1e-9)
+	assert.InDelta(t, 94.79002, net.Net, 1e-9)
+	assert.InDelta(t, net.Gross-net.TotalFees, net.Net, 1e-9)
+}
+
+func TestNetProceedsSlippage(t *testing.T) {
+	// the book only pays 9 sats on average
+	net := netProceeds(100000, 0.0000001, 0.009, 10000, FeeSchedule{}, 0, true)
+	assert.Equal(t, "slippage", net.Fees[1].Name)
+	assert.InDelta(t, 0.001, net.Fees[1].Amount, 1e-12)
+	assert.InDelta(t, 10.0, net.Fees[1].Value, 1e-9)
+	assert.InDelta(t, 90.0, net.Net, 1e-9)
+	assert.InDelta(t, net.Gross-net.TotalFees, net.Net, 1e-9)
+}
+
+func TestNetProceedsFeesMoreThanSale(t *testing.T) {
+	net := netProceeds(1000, 0.0000001, 0.0001, 10000, FeeSchedule{Withdrawal: 0.0005}, 0, false)
+	assert.True(t, net.Net < 0)
+	assert.InDelta(t, -4.0, net.Net, 1e-9)
+}
+
+func TestFeeSource(t *testing.T) {
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.Sources = append(settings.Sources, Source{Name: "other", Kind: "tradeogre", Pair: "BTC-TRTL", Weight: 1, Fees: FeeSchedule{TradePercent: 0.1}})
+	Configure(settings)
+
+	source, err := BtcSource("")
+	assert.Nil(t, err)
+	assert.Equal(t, "tradeogre", source.Name)
+	source, err = BtcSource("other")
+	assert.Nil(t, err)
+	assert.Equal(t, 0.1, source.Fees.TradePercent)
+	_, err = BtcSource("missing")
+	assert.NotNil(t, err)
+}
+
+func TestConvertTurtleNetPricesAtTheSource(t *testing.T) {
+	cheap := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl.json"})
+	defer cheap.Close()
+	rich := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl_rich.json"})
+	defer rich.Close()
+	coinbase := fixtureExchange(t, map[string]string{"/prices/BTC-USD/spot": "coinbase_spot_btc_usd.json"})
+	defer coinbase.Close()
+
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.FiatURL = coinbase.URL
+	settings.Sources = []Source{
+		{Name: "cheap", Kind: "tradeogre", URL: cheap.URL, Pair: "BTC-TRTL", Weight: 1},
+		{Name: "rich", Kind: "tradeogre", URL: rich.URL, Pair: "BTC-TRTL", Weight: 1},
+	}
+	Configure(settings)
+
+	net, err := ConvertTurtleNet(100000, "USD", NetModeSpot, "rich", true)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.00000008, net.SpotPrice)
+}
+
+func TestConvertTurtleNetScreensTheSourcePrice(t *testing.T) {
+	fatFinger := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl_rich.json"})
+	defer fatFinger.Close()
+
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.Anomaly = AnomalySettings{Window: time.Hour, MinSamples: 1, JumpPercent: 50}
+	settings.Sources = []Source{{Name: "a", Kind: "tradeogre", URL: fatFinger.URL, Pair: "BTC-TRTL", Weight: 1}}
+	Configure(settings)
+	history, _ := GetPriceHistory()
+	history.Record(PricePoint{Time: time.Now().UTC(), TrtlToBtc: 0.00000002})
+
+	_, err := ConvertTurtleNet(100000, "USD", NetModeSpot, "a", true)
+	assert.Equal(t, ErrQuotesQuarantined, errors.Cause(err))
+}
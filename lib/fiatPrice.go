@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FiatPrice is an amount of turtle valued in BTC and one fiat currency
+type FiatPrice struct {
+	Trtl      float64 `json:"trtl"`
+	Btc       float64 `json:"btc"`
+	Fiat      string  `json:"fiat"`
+	Value     float64 `json:"value"`
+	fetchedAt time.Time
+	stale     bool
+}
+
+// FetchedAt is when the oldest of the prices was pulled from upstream, zero when it wasn't pulled
+func (price FiatPrice) FetchedAt() time.Time {
+	return price.fetchedAt
+}
+
+// Stale is whether every fresh TRTL quote was quarantined and the last accepted one was used instead
+func (price FiatPrice) Stale() bool {
+	return price.stale
+}
+
+// GetFiatPriceHash gets the price of a single turtle in BTC and a fiat currency like EUR
+func GetFiatPriceHash(fiat string, forceCheck bool) (price FiatPrice, err error) {
+	return GetFiatPriceHashContext(context.Background(), fiat, forceCheck)
+}
+
+// GetFiatPriceHashContext is GetFiatPriceHash that gives up when ctx is done
+func GetFiatPriceHashContext(ctx context.Context, fiat string, forceCheck bool) (price FiatPrice, err error) {
+	fiat = strings.ToUpper(fiat)
+	btcCtx, cancel := budget(ctx, exchangeShare())
+	btc, getBtcPriceErr := getTrtlToBtcQuote(btcCtx, forceCheck)
+	cancel()
+	if getBtcPriceErr != nil {
+		return price, errors.Wrap(getBtcPriceErr, "Problem getting BTC Price")
+	}
+	currentTrtlBtcPrice := btc.price
+
+	currentBtcFiatPrice, fiatFetchedAt, getFiatErr := getBtcToFiatPrice(ctx, fiat, forceCheck)
+	if getFiatErr != nil {
+		return price, errors.Wrapf(getFiatErr, "Problem getting BTC to %s price", fiat)
+	}
+
+	// a stale price was already recorded when it was accepted, recording it again would make it look fresh
+	if !btc.stale {
+		recordPrice(ctx, btc, fiat, currentBtcFiatPrice)
+	}
+	return FiatPrice{
+		Trtl:      1,
+		Btc:       currentTrtlBtcPrice,
+		Fiat:      fiat,
+		Value:     currentTrtlBtcPrice * currentBtcFiatPrice,
+		fetchedAt: olderTime(btc.fetchedAt, fiatFetchedAt),
+		stale:     btc.stale,
+	}, nil
+}
+
+// GetTrtlPrice is the price of a single turtle in BTC or a fiat currency
+func GetTrtlPrice(currency string, forceCheck bool) (price float64, err error) {
+	return GetTrtlPriceContext(context.Background(), currency, forceCheck)
+}
+
+// GetTrtlPriceContext is GetTrtlPrice that gives up when ctx is done
+func GetTrtlPriceContext(ctx context.Context, currency string, forceCheck bool) (price float64, err error) {
+	price, _, _, err = GetTrtlPriceFetchedContext(ctx, currency, forceCheck)
+	return price, err
+}
+
+// GetTrtlPriceFetchedContext is GetTrtlPriceContext that also says when the price was pulled, which is earlier than
+// now when it came from the cache, and whether it's stale because every fresh quote was quarantined
+func GetTrtlPriceFetchedContext(ctx context.Context, currency string, forceCheck bool) (price float64, fetchedAt time.Time, stale bool, err error) {
+	currency = strings.ToUpper(currency)
+	if currency == "BTC" {
+		quote, quoteErr := getTrtlToBtcQuote(ctx, forceCheck)
+		return quote.price, quote.fetchedAt, quote.stale, quoteErr
+	}
+	fiatPrice, getFiatErr := GetFiatPriceHashContext(ctx, currency, forceCheck)
+	return fiatPrice.Value, fiatPrice.fetchedAt, fiatPrice.stale, getFiatErr
+}
+
+// ConvertTurtleFiat converts turtle coins into BTC and a fiat currency
+func ConvertTurtleFiat(trtl float64, fiat string, forceCheck bool) (price FiatPrice, err error) {
+	return ConvertTurtleFiatContext(context.Background(), trtl, fiat, forceCheck)
+}
+
+// ConvertTurtleFiatContext is ConvertTurtleFiat that gives up when ctx is done
+func ConvertTurtleFiatContext(ctx context.Context, trtl float64, fiat string, forceCheck bool) (price FiatPrice, err error) {
+	currentPrice, getCurrentPriceError := GetFiatPriceHashContext(ctx, fiat, forceCheck)
+	if getCurrentPriceError != nil {
+		return price, errors.Wrap(getCurrentPriceError, "Problem getting the current price")
+	}
+	return ConvertTurtleFiatPrice(currentPrice, trtl), nil
+}
+
+// ConvertTurtleFiatPrice scales the price of one turtle up to trtl turtles
+func ConvertTurtleFiatPrice(currentPrice FiatPrice, trtl float64) FiatPrice {
+	return FiatPrice{
+		Trtl:      trtl,
+		Btc:       currentPrice.Btc / currentPrice.Trtl * trtl,
+		Fiat:      currentPrice.Fiat,
+		Value:     currentPrice.Value / currentPrice.Trtl * trtl,
+		fetchedAt: currentPrice.fetchedAt,
+		stale:     currentPrice.stale,
+	}
+}
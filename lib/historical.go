@@ -0,0 +1,195 @@
+package lib
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoPriceAt is returned when nothing was recorded close enough to the requested time
+var ErrNoPriceAt = errors.New("no price was recorded close enough to that time")
+
+// Historical price policies, how a price between two recorded points is worked out
+const (
+	PolicyInterpolate = "interpolate"
+	PolicyNearest     = "nearest"
+)
+
+// UsedPoint is a recorded price a historical price was worked out from and how far it was from the requested time
+type UsedPoint struct {
+	Time     time.Time `json:"time"`
+	Price    float64   `json:"price"`
+	Distance string    `json:"distance"`
+}
+
+// HistoricalLeg is one leg of a historical price, like TRTL-BTC, and the points it came from
+type HistoricalLeg struct {
+	Pair   string      `json:"pair"`
+	Price  float64     `json:"price"`
+	Points []UsedPoint `json:"points"`
+}
+
+// HistoricalPrice is the price of one turtle in a currency at a past time
+type HistoricalPrice struct {
+	At       time.Time       `json:"at"`
+	Currency string          `json:"currency"`
+	Policy   string          `json:"policy"`
+	Price    float64         `json:"price"`
+	Legs     []HistoricalLeg `json:"legs"`
+}
+
+// ParseTime reads a timestamp as RFC 3339, a date like 2018-03-01 (midnight UTC) or unix seconds
+func ParseTime(text string) (at time.Time, err error) {
+	text = strings.TrimSpace(text)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if at, err = time.Parse(layout, text); err == nil {
+			return at.UTC(), nil
+		}
+	}
+	if seconds, parseErr := strconv.ParseInt(text, 10, 64); parseErr == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return at, errors.Errorf("%q is not a time, use RFC 3339, YYYY-MM-DD or unix seconds", text)
+}
+
+// PriceAt works out the price of a turtle in currency (BTC or a fiat) at a past time from the recorded points
+// The TRTL-BTC and BTC-fiat legs are looked up separately since they aren't always recorded together
+// Only points within tolerance of at are used
+func (history *PriceHistory) PriceAt(at time.Time, currency string, policy string, tolerance time.Duration) (price HistoricalPrice, err error) {
+	if policy != PolicyInterpolate && policy != PolicyNearest {
+		return price, errors.Errorf("policy must be %s or %s, not %s", PolicyInterpolate, PolicyNearest, policy)
+	}
+	price = HistoricalPrice{At: at, Currency: currency, Policy: policy}
+
+	trtlLeg, trtlErr := history.legAt("TRTL-BTC", at, policy, tolerance, func(point PricePoint) (float64, bool) {
+		return point.TrtlToBtc, point.TrtlToBtc > 0
+	})
+	if trtlErr != nil {
+		return price, trtlErr
+	}
+	price.Legs = append(price.Legs, trtlLeg)
+	price.Price = trtlLeg.Price
+	if currency == "BTC" {
+		return price, nil
+	}
+
+	fiatLeg, fiatErr := history.legAt("BTC-"+currency, at, policy, tolerance, func(point PricePoint) (float64, bool) {
+		btcToFiat, ok := point.BtcToFiat[currency]
+		return btcToFiat, ok
+	})
+	if fiatErr != nil {
+		return price, fiatErr
+	}
+	price.Legs = append(price.Legs, fiatLeg)
+	price.Price *= fiatLeg.Price
+	return price, nil
+}
+
+// legAt finds the recorded values of one leg either side of at and interpolates between them, or takes the nearest
+func (history *PriceHistory) legAt(pair string, at time.Time, policy string, tolerance time.Duration, value func(PricePoint) (float64, bool)) (leg HistoricalLeg, err error) {
+	history.lock.RLock()
+	defer history.lock.RUnlock()
+
+	leg.Pair = pair
+	next := sort.Search(len(history.points), func(i int) bool { return !history.points[i].Time.Before(at) })
+
+	var before, after *UsedPoint
+	for i := next - 1; i >= 0 && at.Sub(history.points[i].Time) <= tolerance; i-- {
+		if price, ok := value(history.points[i]); ok {
+			before = &UsedPoint{Time: history.points[i].Time, Price: price, Distance: at.Sub(history.points[i].Time).String()}
+			break
+		}
+	}
+	for i := next; i < len(history.points) && history.points[i].Time.Sub(at) <= tolerance; i++ {
+		if price, ok := value(history.points[i]); ok {
+			after = &UsedPoint{Time: history.points[i].Time, Price: price, Distance: history.points[i].Time.Sub(at).String()}
+			break
+		}
+	}
+
+	switch {
+	case before == nil && after == nil:
+		return leg, errors.Wrapf(ErrNoPriceAt, "%s within %s of %s", pair, tolerance, at.Format(time.RFC3339))
+	case after != nil && (before == nil || after.Time.Equal(at)):
+		leg.Points, leg.Price = []UsedPoint{*after}, after.Price
+	case after == nil:
+		leg.Points, leg.Price = []UsedPoint{*before}, before.Price
+	case policy == PolicyNearest:
+		leg.Points, leg.Price = []UsedPoint{*before}, before.Price
+		if after.Time.Sub(at) < at.Sub(before.Time) {
+			leg.Points, leg.Price = []UsedPoint{*after}, after.Price
+		}
+	default:
+		weight := float64(at.Sub(before.Time)) / float64(after.Time.Sub(before.Time))
+		leg.Points = []UsedPoint{*before, *after}
+		leg.Price = before.Price + (after.Price-before.Price)*weight
+	}
+	return leg, nil
+}
+
+// ParseHistoryCSV reads price history in the CSV the history command writes: a TIME column, a TRTL-BTC column
+// and a BTC-<fiat> column for each fiat. Empty cells are left out, so history for only one leg can be imported
+func ParseHistoryCSV(r io.Reader) (points []PricePoint, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, headerErr := reader.Read()
+	if headerErr != nil {
+		return points, errors.Wrap(headerErr, "Problem reading the CSV header")
+	}
+
+	timeColumn, trtlColumn := -1, -1
+	fiatColumns := map[int]string{}
+	for i, name := range header {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		switch {
+		case name == "TIME":
+			timeColumn = i
+		case name == "TRTL-BTC":
+			trtlColumn = i
+		case strings.HasPrefix(name, "BTC-"):
+			fiatColumns[i] = strings.TrimPrefix(name, "BTC-")
+		default:
+			return points, errors.Errorf("column %q should be TIME, TRTL-BTC or BTC-<fiat>", name)
+		}
+	}
+	if timeColumn < 0 {
+		return points, errors.New("the CSV needs a TIME column")
+	}
+
+	for line := 2; ; line++ {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			return points, nil
+		}
+		if readErr != nil {
+			return points, errors.Wrap(readErr, "Problem reading the CSV")
+		}
+
+		point := PricePoint{BtcToFiat: map[string]float64{}}
+		if point.Time, err = ParseTime(record[timeColumn]); err != nil {
+			return points, errors.Wrapf(err, "line %d", line)
+		}
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			if i == timeColumn || cell == "" {
+				continue
+			}
+			value, parseErr := strconv.ParseFloat(cell, 64)
+			if parseErr != nil || value <= 0 {
+				return points, fmt.Errorf("line %d: %q in %s is not a price", line, cell, header[i])
+			}
+			if i == trtlColumn {
+				point.TrtlToBtc = value
+			} else {
+				point.BtcToFiat[fiatColumns[i]] = value
+			}
+		}
+		points = append(points, point)
+	}
+}
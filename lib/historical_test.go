@@ -0,0 +1,62 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceAt(t *testing.T) {
+	history, _ := OpenPriceHistory("")
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	history.Import([]PricePoint{
+		{Time: start, TrtlToBtc: 1e-7, BtcToFiat: map[string]float64{"EUR": 9000}},
+		{Time: start.Add(4 * time.Hour), TrtlToBtc: 3e-7},
+		{Time: start.Add(6 * time.Hour), BtcToFiat: map[string]float64{"EUR": 10000}},
+	})
+
+	at := start.Add(time.Hour)
+	interpolated, err := history.PriceAt(at, "EUR", PolicyInterpolate, 24*time.Hour)
+	assert.Nil(t, err)
+	assert.InDelta(t, 1.5e-7, interpolated.Legs[0].Price, 1e-15)
+	assert.Len(t, interpolated.Legs[0].Points, 2)
+	assert.Equal(t, "1h0m0s", interpolated.Legs[0].Points[0].Distance)
+	assert.InDelta(t, 9000+1000.0/6, interpolated.Legs[1].Price, 1e-9)
+	assert.InDelta(t, 1.5e-7*(9000+1000.0/6), interpolated.Price, 1e-12)
+
+	nearest, err := history.PriceAt(at, "BTC", PolicyNearest, 24*time.Hour)
+	assert.Nil(t, err)
+	assert.Len(t, nearest.Legs, 1)
+	assert.Equal(t, 1e-7, nearest.Price)
+
+	_, err = history.PriceAt(start.Add(-time.Hour), "EUR", PolicyInterpolate, 30*time.Minute)
+	assert.Contains(t, err.Error(), ErrNoPriceAt.Error())
+	_, err = history.PriceAt(at, "GBP", PolicyInterpolate, 24*time.Hour)
+	assert.NotNil(t, err)
+}
+
+func TestParseHistoryCSV(t *testing.T) {
+	points, err := ParseHistoryCSV(strings.NewReader("TIME,TRTL-BTC,BTC-USD\n2018-03-01,0.00000010,10000.00\n1519862400,,11000\n"))
+	assert.Nil(t, err)
+	assert.Len(t, points, 2)
+	assert.Equal(t, time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC), points[0].Time)
+	assert.Equal(t, 1e-7, points[0].TrtlToBtc)
+	assert.Equal(t, 0.0, points[1].TrtlToBtc)
+	assert.Equal(t, 11000.0, points[1].BtcToFiat["USD"])
+
+	history, _ := OpenPriceHistory("")
+	added, err := history.Import(points)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+	imported := history.Range(points[0].Time, points[0].Time)
+	assert.Len(t, imported, 1)
+	assert.Equal(t, 1e-7, imported[0].TrtlToBtc)
+	assert.Equal(t, 10000.0, imported[0].BtcToFiat["USD"])
+
+	_, err = ParseHistoryCSV(strings.NewReader("TIME,PRICE\n"))
+	assert.NotNil(t, err)
+	_, err = ParseHistoryCSV(strings.NewReader("TIME,TRTL-BTC\nyesterday,1\n"))
+	assert.Contains(t, err.Error(), "line 2")
+}
@@ -0,0 +1,332 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoHistory is returned when prices aren't being recorded to a file
+var ErrNoHistory = errors.New("price history is not being recorded, set HISTORY_FILE")
+
+// PricePoint is one recorded observation of TRTL's price
+// Volume is the BTC traded over the 24 hours before it on the sources that were averaged, zero when it wasn't recorded
+type PricePoint struct {
+	Time      time.Time          `json:"time"`
+	TrtlToBtc float64            `json:"trtlToBtc"`
+	Volume    float64            `json:"volume,omitempty"`
+	BtcToFiat map[string]float64 `json:"btcToFiat"`
+}
+
+// Price is the price of one turtle in currency (BTC or a fiat) at the point
+// ok is false when that fiat or, for imported points, the TRTL price wasn't recorded
+func (point PricePoint) Price(currency string) (price float64, ok bool) {
+	if point.TrtlToBtc <= 0 {
+		return price, false
+	}
+	if currency == "BTC" {
+		return point.TrtlToBtc, true
+	}
+	btcToFiat, ok := point.BtcToFiat[currency]
+	return point.TrtlToBtc * btcToFiat, ok
+}
+
+// PriceHistory keeps price points in time order, appending each one to a JSON lines file when it has a path
+type PriceHistory struct {
+	path   string
+	lock   sync.RWMutex
+	points []PricePoint
+}
+
+// OpenPriceHistory loads the points already in path, which is created if it doesn't exist
+// An empty path keeps the history in memory only
+func OpenPriceHistory(path string) (*PriceHistory, error) {
+	history := &PriceHistory{path: path}
+	if path == "" {
+		return history, nil
+	}
+
+	file, openErr := os.Open(path)
+	if os.IsNotExist(openErr) {
+		return history, nil
+	}
+	if openErr != nil {
+		return history, openErr
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		point := PricePoint{}
+		if jsonErr := json.Unmarshal(scanner.Bytes(), &point); jsonErr != nil {
+			return history, errors.Wrapf(jsonErr, "%s line %d", path, line)
+		}
+		history.points = append(history.points, point)
+	}
+	history.sort()
+	history.mergeSameTime()
+	history.prune(time.Now())
+	return history, scanner.Err()
+}
+
+// Path is where the history is saved, empty when it's only in memory
+func (history *PriceHistory) Path() string {
+	return history.path
+}
+
+// Record adds a point to the history
+func (history *PriceHistory) Record(point PricePoint) error {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+	return history.record([]PricePoint{point})
+}
+
+// Import adds points to the history, a point at a time that's already recorded fills in the prices it's missing
+// added is how many points were new or filled something in
+func (history *PriceHistory) Import(points []PricePoint) (added int, err error) {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+
+	recorded := map[int64]int{}
+	for i, point := range history.points {
+		recorded[point.Time.UnixNano()] = i
+	}
+	fresh := map[int64]int{}
+	var freshPoints []PricePoint
+	filled := map[int]PricePoint{}
+	for _, point := range points {
+		key := point.Time.UnixNano()
+		if i, ok := fresh[key]; ok {
+			freshPoints[i], _ = mergePoints(freshPoints[i], point)
+			continue
+		}
+		if i, ok := recorded[key]; ok {
+			existing, seen := filled[i]
+			if !seen {
+				existing = history.points[i]
+			}
+			if merged, changed := mergePoints(existing, point); changed {
+				filled[i] = merged
+			}
+			continue
+		}
+		fresh[key] = len(freshPoints)
+		freshPoints = append(freshPoints, point)
+	}
+
+	// the filled in points are appended again, loading the file merges them back into one
+	var changed []PricePoint
+	for _, point := range filled {
+		changed = append(changed, point)
+	}
+	if saveErr := history.save(append(changed, freshPoints...)); saveErr != nil {
+		return 0, saveErr
+	}
+	for i, point := range filled {
+		history.points[i] = point
+	}
+	history.add(freshPoints)
+	return len(filled) + len(freshPoints), nil
+}
+
+// mergePoints fills in the prices and volume point is missing from other, changed is whether there were any
+func mergePoints(point PricePoint, other PricePoint) (merged PricePoint, changed bool) {
+	merged = point
+	if merged.TrtlToBtc <= 0 && other.TrtlToBtc > 0 {
+		merged.TrtlToBtc = other.TrtlToBtc
+		changed = true
+	}
+	if merged.Volume <= 0 && other.Volume > 0 {
+		merged.Volume = other.Volume
+		changed = true
+	}
+	copied := false
+	for fiat, btcToFiat := range other.BtcToFiat {
+		if _, ok := merged.BtcToFiat[fiat]; ok {
+			continue
+		}
+		// the map is shared with the copies Range hands out, so it's copied before it's changed
+		if !copied {
+			fiats := make(map[string]float64, len(merged.BtcToFiat)+1)
+			for existing, value := range merged.BtcToFiat {
+				fiats[existing] = value
+			}
+			merged.BtcToFiat, copied = fiats, true
+		}
+		merged.BtcToFiat[fiat] = btcToFiat
+		changed = true
+	}
+	return merged, changed
+}
+
+// record appends points to the file and the history, the lock must be held
+func (history *PriceHistory) record(points []PricePoint) error {
+	if saveErr := history.save(points); saveErr != nil {
+		return saveErr
+	}
+	history.add(points)
+	return nil
+}
+
+// save appends points to the file when there is one, the lock must be held
+func (history *PriceHistory) save(points []PricePoint) error {
+	if len(points) == 0 || history.path == "" {
+		return nil
+	}
+	file, openErr := os.OpenFile(history.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if openErr != nil {
+		return openErr
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, point := range points {
+		line, jsonErr := json.Marshal(point)
+		if jsonErr != nil {
+			return jsonErr
+		}
+		writer.Write(append(line, '\n'))
+	}
+	return writer.Flush()
+}
+
+// add puts points in the history in time order, the lock must be held
+func (history *PriceHistory) add(points []PricePoint) {
+	if len(points) == 0 {
+		return
+	}
+	last := len(history.points)
+	history.points = append(history.points, points...)
+	for i := last; i < len(history.points); i++ {
+		if i > 0 && history.points[i-1].Time.After(history.points[i].Time) {
+			history.sort()
+			break
+		}
+	}
+	history.prune(time.Now())
+}
+
+// prune forgets the points older than the retention window, they're still in the file; the lock must be held
+func (history *PriceHistory) prune(now time.Time) {
+	retention := CurrentSettings().HistoryRetention
+	if retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-retention)
+	kept := sort.Search(len(history.points), func(i int) bool { return !history.points[i].Time.Before(cutoff) })
+	history.points = history.points[kept:]
+}
+
+// Range returns the points recorded between from and to (inclusive)
+func (history *PriceHistory) Range(from time.Time, to time.Time) []PricePoint {
+	history.lock.RLock()
+	defer history.lock.RUnlock()
+
+	start := sort.Search(len(history.points), func(i int) bool { return !history.points[i].Time.Before(from) })
+	end := sort.Search(len(history.points), func(i int) bool { return history.points[i].Time.After(to) })
+	if start >= end {
+		return nil
+	}
+	points := make([]PricePoint, end-start)
+	copy(points, history.points[start:end])
+	return points
+}
+
+// Change is how far TRTL's price in currency (BTC or a fiat) has moved from the first price recorded in the window
+// before now, as a percent. ok is false when nothing was recorded for currency in the window
+func (history *PriceHistory) Change(currency string, current float64, now time.Time, window time.Duration) (percent float64, ok bool) {
+	for _, point := range history.Range(now.Add(-window), now) {
+		previous, hasPrice := point.Price(currency)
+		if !hasPrice || previous == 0 {
+			continue
+		}
+		return (current - previous) / previous * 100, true
+	}
+	return percent, false
+}
+
+// Last is the most recent price recorded in currency (BTC or a fiat), ok is false when there isn't one
+func (history *PriceHistory) Last(currency string) (sample PriceSample, ok bool) {
+	history.lock.RLock()
+	defer history.lock.RUnlock()
+
+	for i := len(history.points) - 1; i >= 0; i-- {
+		if price, hasPrice := history.points[i].Price(currency); hasPrice {
+			return PriceSample{Time: history.points[i].Time, Price: price}, true
+		}
+	}
+	return sample, false
+}
+
+// mergeSameTime folds points recorded at the same time into the first of them, imports append the prices they fill
+// in as another point at that time; the points must be sorted
+func (history *PriceHistory) mergeSameTime() {
+	var merged []PricePoint
+	for _, point := range history.points {
+		if last := len(merged) - 1; last >= 0 && merged[last].Time.Equal(point.Time) {
+			merged[last], _ = mergePoints(merged[last], point)
+			continue
+		}
+		merged = append(merged, point)
+	}
+	history.points = merged
+}
+
+func (history *PriceHistory) sort() {
+	sort.SliceStable(history.points, func(i, j int) bool { return history.points[i].Time.Before(history.points[j].Time) })
+}
+
+var (
+	defaultHistory     *PriceHistory
+	defaultHistoryErr  error
+	defaultHistoryOnce sync.Once
+)
+
+// GetPriceHistory returns the history kept in $HISTORY_FILE (in memory when it isn't set)
+func GetPriceHistory() (*PriceHistory, error) {
+	defaultHistoryOnce.Do(func() {
+		defaultHistory, defaultHistoryErr = OpenPriceHistory(os.Getenv("HISTORY_FILE"))
+	})
+	return defaultHistory, defaultHistoryErr
+}
+
+var (
+	lastRecordedLock sync.Mutex
+	lastRecorded     = map[string][2]float64{}
+)
+
+// recordPrice saves a freshly pulled price to the history, problems are only logged
+// Prices that haven't changed since the last one recorded for fiat, like those served from the cache, are skipped
+func recordPrice(ctx context.Context, btc btcQuote, fiat string, btcToFiat float64) {
+	lastRecordedLock.Lock()
+	unchanged := lastRecorded[fiat] == [2]float64{btc.price, btcToFiat}
+	lastRecorded[fiat] = [2]float64{btc.price, btcToFiat}
+	lastRecordedLock.Unlock()
+	if unchanged {
+		return
+	}
+
+	history, historyErr := GetPriceHistory()
+	if historyErr != nil {
+		Logger(ctx).Error("Problem opening the price history", "error", historyErr.Error())
+		return
+	}
+	recordErr := history.Record(PricePoint{
+		Time:      time.Now().UTC(),
+		TrtlToBtc: btc.price,
+		Volume:    btc.volume,
+		BtcToFiat: map[string]float64{fiat: btcToFiat},
+	})
+	if recordErr != nil {
+		Logger(ctx).Error("Problem recording the price", "error", recordErr.Error())
+	}
+}
@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceHistoryRecordAndReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.jsonl")
+
+	history, err := OpenPriceHistory(path)
+	assert.Nil(t, err)
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, offset := range []int{0, 2, 1} {
+		err = history.Record(PricePoint{
+			Time:      start.Add(time.Duration(offset) * time.Hour),
+			TrtlToBtc: float64(offset),
+			BtcToFiat: map[string]float64{"USD": 10000},
+		})
+		assert.Nil(t, err)
+	}
+
+	reloaded, err := OpenPriceHistory(path)
+	assert.Nil(t, err)
+	points := reloaded.Range(start, start.Add(time.Hour))
+	assert.Len(t, points, 2)
+	assert.Equal(t, 0.0, points[0].TrtlToBtc)
+	assert.Equal(t, 1.0, points[1].TrtlToBtc)
+	assert.Equal(t, history.Range(start, start.Add(2*time.Hour)), reloaded.Range(start, start.Add(2*time.Hour)))
+}
+
+func TestPriceHistoryImportMergesLegs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.jsonl")
+
+	history, err := OpenPriceHistory(path)
+	assert.Nil(t, err)
+	at := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	added, err := history.Import([]PricePoint{{Time: at, TrtlToBtc: 1e-7}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+	before := history.Range(at, at)
+
+	// the recorded TRTL price is kept, the missing USD one is filled in
+	added, err = history.Import([]PricePoint{{Time: at, TrtlToBtc: 2e-7, BtcToFiat: map[string]float64{"USD": 10000}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+	added, err = history.Import([]PricePoint{{Time: at, BtcToFiat: map[string]float64{"USD": 11000}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, added)
+	assert.Empty(t, before[0].BtcToFiat)
+
+	reloaded, err := OpenPriceHistory(path)
+	assert.Nil(t, err)
+	for _, opened := range []*PriceHistory{history, reloaded} {
+		points := opened.Range(at, at)
+		assert.Len(t, points, 1)
+		price, ok := points[0].Price("USD")
+		assert.True(t, ok)
+		assert.InDelta(t, 1e-3, price, 1e-12)
+	}
+}
+
+func TestPriceHistoryChange(t *testing.T) {
+	history, _ := OpenPriceHistory("")
+	now := time.Date(2018, 3, 2, 0, 0, 0, 0, time.UTC)
+	history.Record(PricePoint{Time: now.Add(-30 * time.Hour), TrtlToBtc: 1e-7, BtcToFiat: map[string]float64{"USD": 5000}})
+	history.Record(PricePoint{Time: now.Add(-20 * time.Hour), TrtlToBtc: 2e-7, BtcToFiat: map[string]float64{"EUR": 9000}})
+	history.Record(PricePoint{Time: now.Add(-10 * time.Hour), TrtlToBtc: 4e-7, BtcToFiat: map[string]float64{"USD": 10000}})
+
+	usd, ok := history.Change("USD", 6e-3, now, 24*time.Hour)
+	assert.True(t, ok)
+	assert.InDelta(t, 50, usd, 1e-9)
+
+	btc, ok := history.Change("BTC", 3e-7, now, 24*time.Hour)
+	assert.True(t, ok)
+	assert.InDelta(t, 50, btc, 1e-9)
+
+	_, ok = history.Change("GBP", 1, now, 24*time.Hour)
+	assert.False(t, ok)
+}
+
+func TestHistoryRetention(t *testing.T) {
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.HistoryRetention = 24 * time.Hour
+	Configure(settings)
+
+	now := time.Now().UTC()
+	history, _ := OpenPriceHistory("")
+	history.Record(PricePoint{Time: now.Add(-48 * time.Hour), TrtlToBtc: 1e-7})
+	history.Record(PricePoint{Time: now.Add(-time.Hour), TrtlToBtc: 2e-7})
+
+	points := history.Range(now.Add(-72*time.Hour), now)
+	assert.Len(t, points, 1)
+	assert.Equal(t, 2e-7, points[0].TrtlToBtc)
+}
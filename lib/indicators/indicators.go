@@ -0,0 +1,190 @@
+// Package indicators computes technical indicators over price bars
+// Values that aren't defined yet, while an indicator is warming up or after a gap, are NaN in the slice functions and
+// nil in Points
+package indicators
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Indicator names Compute knows
+const (
+	SMA  = "sma"
+	EMA  = "ema"
+	RSI  = "rsi"
+	VWAP = "vwap"
+)
+
+// Names are the indicators Compute knows, in the order they're documented
+var Names = []string{SMA, EMA, RSI, VWAP}
+
+// Bar is one interval of prices and how much traded in it
+type Bar struct {
+	Time   time.Time
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// Point is an indicator's value at the end of the bar starting at Time, nil when it isn't defined there
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
+}
+
+// Compute works out the named indicator over bars that are interval apart
+// Bars further apart than interval are a gap, the indicator starts warming up again after one rather than
+// smoothing over prices it never saw
+func Compute(name string, bars []Bar, period int, interval time.Duration) (points []Point, err error) {
+	if name != SMA && name != EMA && name != RSI && name != VWAP {
+		return points, errors.Errorf("indicator must be sma, ema, rsi or vwap, not %s", name)
+	}
+	if period < 1 {
+		return points, errors.Errorf("period must be at least 1, not %d", period)
+	}
+
+	points = make([]Point, 0, len(bars))
+	for _, run := range Runs(bars, interval) {
+		closes := make([]float64, len(run))
+		for i, bar := range run {
+			closes[i] = bar.Close
+		}
+
+		var values []float64
+		switch name {
+		case SMA:
+			values = SimpleMovingAverage(closes, period)
+		case EMA:
+			values = ExponentialMovingAverage(closes, period)
+		case RSI:
+			values = RelativeStrengthIndex(closes, period)
+		case VWAP:
+			typical, volumes := make([]float64, len(run)), make([]float64, len(run))
+			for i, bar := range run {
+				typical[i] = (bar.High + bar.Low + bar.Close) / 3
+				volumes[i] = bar.Volume
+			}
+			values = VolumeWeightedAveragePrice(typical, volumes, period)
+		}
+
+		for i, value := range values {
+			point := Point{Time: run[i].Time}
+			if !math.IsNaN(value) {
+				value := value
+				point.Value = &value
+			}
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
+// Runs splits bars in time order wherever two are more than interval apart
+func Runs(bars []Bar, interval time.Duration) (runs [][]Bar) {
+	start := 0
+	for i := 1; i <= len(bars); i++ {
+		if i == len(bars) || bars[i].Time.Sub(bars[i-1].Time) > interval {
+			runs = append(runs, bars[start:i])
+			start = i
+		}
+	}
+	return runs
+}
+
+// SimpleMovingAverage is the mean of the last period values
+func SimpleMovingAverage(values []float64, period int) []float64 {
+	averages := undefined(len(values))
+	sum := 0.0
+	for i, value := range values {
+		sum += value
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			averages[i] = sum / float64(period)
+		}
+	}
+	return averages
+}
+
+// ExponentialMovingAverage weights each value by 2/(period+1), starting from the simple average of the first period
+func ExponentialMovingAverage(values []float64, period int) []float64 {
+	averages := undefined(len(values))
+	if len(values) < period {
+		return averages
+	}
+	weight := 2 / float64(period+1)
+	averages[period-1] = SimpleMovingAverage(values[:period], period)[period-1]
+	for i := period; i < len(values); i++ {
+		averages[i] = averages[i-1] + weight*(values[i]-averages[i-1])
+	}
+	return averages
+}
+
+// RelativeStrengthIndex is Wilder's RSI: 100 - 100/(1 + average gain / average loss), with the averages smoothed
+// over period changes, so the first value is at index period
+func RelativeStrengthIndex(values []float64, period int) []float64 {
+	indexes := undefined(len(values))
+	if len(values) <= period {
+		return indexes
+	}
+
+	gain, loss := 0.0, 0.0
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		gain += math.Max(change, 0)
+		loss += math.Max(-change, 0)
+	}
+	gain, loss = gain/float64(period), loss/float64(period)
+	indexes[period] = rsi(gain, loss)
+
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		gain = (gain*float64(period-1) + math.Max(change, 0)) / float64(period)
+		loss = (loss*float64(period-1) + math.Max(-change, 0)) / float64(period)
+		indexes[i] = rsi(gain, loss)
+	}
+	return indexes
+}
+
+func rsi(gain float64, loss float64) float64 {
+	if loss == 0 {
+		if gain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+// VolumeWeightedAveragePrice is the average of the last period prices weighted by their volumes
+// It isn't defined over a window with no volume at all
+func VolumeWeightedAveragePrice(prices []float64, volumes []float64, period int) []float64 {
+	averages := undefined(len(prices))
+	weighted, volume := 0.0, 0.0
+	for i := range prices {
+		weighted += prices[i] * volumes[i]
+		volume += volumes[i]
+		if i >= period {
+			weighted -= prices[i-period] * volumes[i-period]
+			volume -= volumes[i-period]
+		}
+		if i >= period-1 && volume > 0 {
+			averages[i] = weighted / volume
+		}
+	}
+	return averages
+}
+
+// undefined is n NaNs
+func undefined(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	return values
+}
@@ -0,0 +1,91 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stockChartsCloses is the 10 day moving average example from StockCharts' ChartSchool
+var stockChartsCloses = []float64{
+	22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+	22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+	23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+}
+
+// assertReference checks values against reference values rounded to 2 places, starting at index from
+func assertReference(t *testing.T, expected []float64, values []float64, from int) {
+	for i := 0; i < from; i++ {
+		assert.True(t, math.IsNaN(values[i]), "index %d should be undefined", i)
+	}
+	for i, want := range expected {
+		assert.InDelta(t, want, values[from+i], 0.0051, "index %d", from+i)
+	}
+}
+
+func TestSimpleMovingAverage(t *testing.T) {
+	assertReference(t, []float64{
+		22.22, 22.21, 22.23, 22.26, 22.30, 22.42, 22.61, 22.77, 22.91, 23.08,
+		23.21, 23.38, 23.52, 23.65, 23.71, 23.68, 23.61, 23.51, 23.43, 23.28, 23.13,
+	}, SimpleMovingAverage(stockChartsCloses, 10), 9)
+}
+
+func TestExponentialMovingAverage(t *testing.T) {
+	assertReference(t, []float64{
+		22.22, 22.21, 22.24, 22.27, 22.33, 22.52, 22.80, 22.97, 23.13, 23.28,
+		23.34, 23.43, 23.51, 23.53, 23.47, 23.40, 23.39, 23.26, 23.23, 23.08, 22.92,
+	}, ExponentialMovingAverage(stockChartsCloses, 10), 9)
+	assert.True(t, math.IsNaN(ExponentialMovingAverage([]float64{1, 2}, 3)[1]))
+}
+
+func TestRelativeStrengthIndex(t *testing.T) {
+	// StockCharts' RSI example
+	closes := []float64{
+		44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826,
+		45.8931, 46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439,
+	}
+	assertReference(t, []float64{70.53, 66.32, 66.55, 69.41, 66.36, 57.97}, RelativeStrengthIndex(closes, 14), 14)
+
+	flat := RelativeStrengthIndex([]float64{1, 1, 1}, 2)
+	assert.Equal(t, 50.0, flat[2])
+	rising := RelativeStrengthIndex([]float64{1, 2, 3}, 2)
+	assert.Equal(t, 100.0, rising[2])
+}
+
+func TestVolumeWeightedAveragePrice(t *testing.T) {
+	values := VolumeWeightedAveragePrice([]float64{10, 20, 30, 40}, []float64{1, 3, 0, 0}, 2)
+	assert.True(t, math.IsNaN(values[0]))
+	assert.Equal(t, 17.5, values[1])
+	assert.Equal(t, 20.0, values[2])
+	assert.True(t, math.IsNaN(values[3]), "no volume in the window")
+}
+
+func TestComputeRestartsAfterAGap(t *testing.T) {
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	var bars []Bar
+	for _, hour := range []int{0, 1, 2, 5, 6} {
+		bars = append(bars, Bar{Time: start.Add(time.Duration(hour) * time.Hour), Close: float64(hour)})
+	}
+
+	points, err := Compute(SMA, bars, 2, time.Hour)
+	assert.Nil(t, err)
+	assert.Len(t, points, 5)
+	assert.Nil(t, points[0].Value)
+	assert.Equal(t, 0.5, *points[1].Value)
+	assert.Equal(t, 1.5, *points[2].Value)
+	assert.Nil(t, points[3].Value, "the average doesn't reach back across the gap")
+	assert.Equal(t, 5.5, *points[4].Value)
+	assert.Equal(t, bars[4].Time, points[4].Time)
+}
+
+func TestComputeErrors(t *testing.T) {
+	_, err := Compute("macd", nil, 14, time.Hour)
+	assert.NotNil(t, err)
+	_, err = Compute(SMA, nil, 0, time.Hour)
+	assert.NotNil(t, err)
+	points, err := Compute(RSI, nil, 14, time.Hour)
+	assert.Nil(t, err)
+	assert.Empty(t, points)
+}
@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+type requestIDKey struct{}
+
+var logger atomic.Pointer[slog.Logger]
+
+func init() {
+	logger.Store(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+}
+
+// SetupLogging sets the level (debug, info, warn or error) and format (json or text) of everything lib and the server log
+func SetupLogging(w io.Writer, level string, format string) {
+	var slogLevel slog.Level
+	if err := slogLevel.UnmarshalText([]byte(level)); err != nil {
+		slogLevel = slog.LevelInfo
+	}
+	options := &slog.HandlerOptions{Level: slogLevel}
+
+	if strings.ToLower(format) == "text" {
+		logger.Store(slog.New(slog.NewTextHandler(w, options)))
+	} else {
+		logger.Store(slog.New(slog.NewJSONHandler(w, options)))
+	}
+}
+
+// WithRequestID puts a request ID into ctx so everything logged for the request can be tied together
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID is the request ID in ctx, empty when there isn't one
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Logger is the structured logger, tagged with ctx's request ID when it has one
+func Logger(ctx context.Context) *slog.Logger {
+	if requestID := RequestID(ctx); requestID != "" {
+		return logger.Load().With("request_id", requestID)
+	}
+	return logger.Load()
+}
@@ -0,0 +1,179 @@
+package lib
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MarketStats is a source's 24h ticker normalized so every exchange looks the same
+// Bid, Ask and SpreadPercent are nil when the source doesn't provide a book
+type MarketStats struct {
+	Source        string    `json:"source"`
+	Pair          string    `json:"pair"`
+	Open          float64   `json:"open"`
+	High          float64   `json:"high"`
+	Low           float64   `json:"low"`
+	Last          float64   `json:"last"`
+	ChangePercent float64   `json:"changePercent"`
+	Volume        float64   `json:"volume"`
+	Fiat          string    `json:"fiat,omitempty"`
+	FiatVolume    float64   `json:"fiatVolume,omitempty"`
+	Bid           *float64  `json:"bid,omitempty"`
+	Ask           *float64  `json:"ask,omitempty"`
+	SpreadPercent *float64  `json:"spreadPercent,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// PullMarketStats gets the 24h stats for a source's market
+func PullMarketStats(source Source) (stats MarketStats, err error) {
+	return PullMarketStatsContext(context.Background(), source)
+}
+
+// PullMarketStatsContext is PullMarketStats that gives up when ctx is done
+func PullMarketStatsContext(ctx context.Context, source Source) (stats MarketStats, err error) {
+	ticker, tickerErr := pullTradeOgreTicker(ctx, source)
+	if tickerErr != nil {
+		return stats, tickerErr
+	}
+	stats, err = tradeOgreMarketStats(ticker)
+	stats.Source = source.Name
+	stats.Pair = source.Pair
+	return stats, err
+}
+
+// getMarketStats is PullMarketStatsContext from the cached ticker when it was pulled within the TRTL cache TTL
+// UpdatedAt is when the ticker was pulled
+func getMarketStats(ctx context.Context, source Source, forceCheck bool) (stats MarketStats, err error) {
+	ticker, fetchedAt, tickerErr := getTradeOgreTicker(ctx, source, forceCheck)
+	if tickerErr != nil {
+		return stats, tickerErr
+	}
+	stats, err = tradeOgreMarketStats(ticker)
+	stats.Source, stats.Pair, stats.UpdatedAt = source.Name, source.Pair, fetchedAt.UTC()
+	return stats, err
+}
+
+func tradeOgreMarketStats(ticker tradeOgreTicker) (stats MarketStats, err error) {
+	fields := []struct {
+		value string
+		dest  *float64
+	}{
+		{ticker.InitialPrice, &stats.Open},
+		{ticker.High, &stats.High},
+		{ticker.Low, &stats.Low},
+		{ticker.Price, &stats.Last},
+		{ticker.Volume, &stats.Volume},
+	}
+	for _, field := range fields {
+		*field.dest, err = strconv.ParseFloat(field.value, 64)
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	if ticker.Bid != "" && ticker.Ask != "" {
+		bid, bidErr := strconv.ParseFloat(ticker.Bid, 64)
+		ask, askErr := strconv.ParseFloat(ticker.Ask, 64)
+		if bidErr == nil && askErr == nil {
+			stats.Bid = &bid
+			stats.Ask = &ask
+			if ask > 0 {
+				spread := (ask - bid) / ask * 100
+				stats.SpreadPercent = &spread
+			}
+		}
+	}
+
+	if stats.Open > 0 {
+		stats.ChangePercent = (stats.Last - stats.Open) / stats.Open * 100
+	}
+	stats.UpdatedAt = time.Now().UTC()
+	return stats, nil
+}
+
+// GetMarketStats pulls 24h stats from every configured source with the volume valued in fiat
+// A source that fails is skipped, it's only an error when none of them work
+func GetMarketStats(fiat string, forceCheck bool) (markets []MarketStats, err error) {
+	return GetMarketStatsContext(context.Background(), fiat, forceCheck)
+}
+
+// GetMarketStatsContext is GetMarketStats that gives up when ctx is done
+func GetMarketStatsContext(ctx context.Context, fiat string, forceCheck bool) (markets []MarketStats, err error) {
+	fiat = strings.ToUpper(fiat)
+	fiatCtx, cancel := budget(ctx, 1-exchangeShare())
+	btcToFiat, getFiatErr := GetBtcToFiatPriceContext(fiatCtx, fiat, forceCheck)
+	cancel()
+	if getFiatErr != nil {
+		return markets, errors.Wrapf(getFiatErr, "Problem getting BTC to %s price", fiat)
+	}
+
+	markets, err = PullMarketsContext(ctx, forceCheck)
+	for i := range markets {
+		// volume is in the pair's base currency so it can only be valued for BTC markets
+		if strings.HasPrefix(markets[i].Pair, "BTC-") {
+			markets[i].Fiat = fiat
+			markets[i].FiatVolume = markets[i].Volume * btcToFiat
+		}
+	}
+	return markets, err
+}
+
+// PullMarkets pulls 24h stats from every configured source, without valuing them in a fiat
+// A ticker pulled within the TRTL cache TTL is reused unless forceCheck is set
+// A source that fails is skipped, it's only an error when none of them work
+func PullMarkets(forceCheck bool) (markets []MarketStats, err error) {
+	return PullMarketsContext(context.Background(), forceCheck)
+}
+
+// PullMarketsContext is PullMarkets that gives up when ctx is done
+func PullMarketsContext(ctx context.Context, forceCheck bool) (markets []MarketStats, err error) {
+	var sourceErrs []string
+	for _, source := range CurrentSettings().Sources {
+		if source.Kind != "tradeogre" {
+			continue
+		}
+		stats, fetchErr := getMarketStats(ctx, source, forceCheck)
+		if fetchErr != nil {
+			sourceErrs = append(sourceErrs, source.Name+": "+fetchErr.Error())
+			continue
+		}
+		markets = append(markets, stats)
+	}
+
+	if len(markets) == 0 {
+		return markets, errors.Errorf("Problem getting market stats: %s", strings.Join(sourceErrs, "; "))
+	}
+	markets = screenMarkets(ctx, markets)
+	if len(markets) == 0 {
+		return markets, ErrQuotesQuarantined
+	}
+	return markets, nil
+}
+
+// screenMarkets leaves out the BTC-TRTL markets whose last trade looks wrong, the same check the published price
+// gets. The other pairs have no recorded price to check against
+func screenMarkets(ctx context.Context, markets []MarketStats) (screened []MarketStats) {
+	var quotes []sourceQuote
+	for _, market := range markets {
+		if strings.ToUpper(market.Pair) == "BTC-TRTL" {
+			quotes = append(quotes, sourceQuote{source: Source{Name: market.Source, Pair: market.Pair}, price: market.Last})
+		}
+	}
+	accepted := map[string]bool{}
+	for _, quote := range checkQuotes(quotes, recentBtcSamples(CurrentSettings().Anomaly)) {
+		accepted[quote.source.Name] = true
+	}
+
+	for _, market := range markets {
+		if strings.ToUpper(market.Pair) == "BTC-TRTL" && !accepted[market.Source] {
+			Logger(ctx).Debug("Leaving out a market whose last trade looks wrong", "source", market.Source, "price", market.Last)
+			continue
+		}
+		screened = append(screened, market)
+	}
+	return screened
+}
@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTickerFixture(t *testing.T, name string) tradeOgreTicker {
+	body, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticker, err := parseTradeOgreTicker(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ticker
+}
+
+func TestTradeOgreMarketStats(t *testing.T) {
+	stats, err := tradeOgreMarketStats(loadTickerFixture(t, "tradeogre_ticker_btc_trtl.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0.00000004, stats.Open)
+	assert.Equal(t, 0.00000006, stats.High)
+	assert.Equal(t, 0.00000003, stats.Low)
+	assert.Equal(t, 0.00000005, stats.Last)
+	assert.Equal(t, 17.18630467, stats.Volume)
+	assert.InDelta(t, 25.0, stats.ChangePercent, 1e-9)
+	assert.Equal(t, 0.00000004, *stats.Bid)
+	assert.Equal(t, 0.00000005, *stats.Ask)
+	assert.InDelta(t, 20.0, *stats.SpreadPercent, 1e-9)
+}
+
+func TestTradeOgreMarketStatsWithoutBook(t *testing.T) {
+	stats, err := tradeOgreMarketStats(loadTickerFixture(t, "tradeogre_ticker_no_book.json"))
+	assert.Nil(t, err)
+	assert.InDelta(t, 60.0, stats.ChangePercent, 1e-9)
+	assert.Nil(t, stats.Bid)
+	assert.Nil(t, stats.Ask)
+	assert.Nil(t, stats.SpreadPercent)
+}
+
+func TestPullMarketsCachesTickers(t *testing.T) {
+	exchange := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl.json"})
+	defer exchange.Close()
+	pulls := 0
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pulls++
+		exchange.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.TrtlCacheTTL = time.Minute
+	settings.Sources = []Source{{Name: "tradeogre", Kind: "tradeogre", URL: counting.URL, Pair: "BTC-TRTL", Weight: 1}}
+	Configure(settings)
+
+	first, err := PullMarkets(false)
+	assert.Nil(t, err)
+	cached, err := PullMarkets(false)
+	assert.Nil(t, err)
+	if assert.Len(t, first, 1) && assert.Len(t, cached, 1) {
+		assert.True(t, first[0].UpdatedAt.Equal(cached[0].UpdatedAt), "a cached ticker keeps when it was pulled")
+	}
+	_, err = PullMarkets(true)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, pulls)
+}
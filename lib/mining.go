@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MiningInput describes a rig; Difficulty and Height are only needed when no daemon is configured
+type MiningInput struct {
+	Hashrate   float64 // hashes per second
+	Watts      float64
+	PowerCost  float64 // fiat per kWh
+	Fiat       string
+	Difficulty uint64
+	Height     uint64
+}
+
+// MiningProfit is what a rig is expected to make and spend in a day
+type MiningProfit struct {
+	Hashrate         float64 `json:"hashrate"`
+	Difficulty       uint64  `json:"difficulty"`
+	DifficultySource string  `json:"difficultySource"`
+	Height           uint64  `json:"height"`
+	BlockReward      float64 `json:"blockReward"`
+	TrtlPrice        float64 `json:"trtlPrice"`
+	Fiat             string  `json:"fiat"`
+	DailyTrtl        float64 `json:"dailyTrtl"`
+	DailyRevenue     float64 `json:"dailyRevenue"`
+	DailyPowerCost   float64 `json:"dailyPowerCost"`
+	DailyProfit      float64 `json:"dailyProfit"`
+}
+
+// GetMiningProfit fills in the network difficulty and reward (from the daemon if there is one) and the TRTL price
+func GetMiningProfit(input MiningInput, forceCheck bool) (profit MiningProfit, err error) {
+	return GetMiningProfitContext(context.Background(), input, forceCheck)
+}
+
+// GetMiningProfitContext is GetMiningProfit that gives up when ctx is done
+func GetMiningProfitContext(ctx context.Context, input MiningInput, forceCheck bool) (profit MiningProfit, err error) {
+	input.Fiat = strings.ToUpper(input.Fiat)
+	difficultySource := "input"
+	var blockReward uint64
+
+	daemon, daemonErr := GetDaemon()
+	if daemonErr == nil && input.Difficulty == 0 {
+		daemonCtx, cancel := budget(ctx, 0.5)
+		header, headerErr := daemon.GetLastBlockHeaderContext(daemonCtx)
+		cancel()
+		if headerErr != nil {
+			return profit, errors.Wrap(headerErr, "Problem getting the network difficulty")
+		}
+		input.Difficulty = header.Difficulty
+		input.Height = header.Height
+		blockReward = header.Reward
+		difficultySource = "daemon"
+	} else if input.Height == 0 {
+		input.Height = EstimateHeight(time.Now())
+	}
+	if input.Difficulty == 0 {
+		return profit, errors.New("difficulty is needed when no daemon is configured")
+	}
+	// the reward walks the chain up to the height, so it's only worked out once the height is settled
+	if difficultySource == "input" {
+		blockReward = BlockRewardAt(input.Height)
+	}
+
+	// a fiat other than USD needs a third upstream call, so leave time for it
+	priceCtx, cancel := context.WithCancel(ctx)
+	if input.Fiat != "USD" {
+		cancel()
+		priceCtx, cancel = budget(ctx, exchangeShare())
+	}
+	price, getPriceErr := GetPriceHashContext(priceCtx, forceCheck)
+	cancel()
+	if getPriceErr != nil {
+		return profit, errors.Wrap(getPriceErr, "Problem getting the current price")
+	}
+	trtlPrice := price.usdPrice
+	if input.Fiat != "USD" {
+		btcToFiat, getFiatErr := GetBtcToFiatPriceContext(ctx, input.Fiat, forceCheck)
+		if getFiatErr != nil {
+			return profit, errors.Wrapf(getFiatErr, "Problem getting BTC to %s price", input.Fiat)
+		}
+		trtlPrice = price.btcPrice * btcToFiat
+	}
+
+	profit = CalculateMiningProfit(input, AtomicToTrtl(blockReward), trtlPrice)
+	profit.DifficultySource = difficultySource
+	return profit, nil
+}
+
+// CalculateMiningProfit does the math: a rig finds hashrate*86400/difficulty blocks a day on average
+func CalculateMiningProfit(input MiningInput, blockReward float64, trtlPrice float64) MiningProfit {
+	profit := MiningProfit{
+		Hashrate:    input.Hashrate,
+		Difficulty:  input.Difficulty,
+		Height:      input.Height,
+		BlockReward: blockReward,
+		TrtlPrice:   trtlPrice,
+		Fiat:        input.Fiat,
+	}
+	if input.Difficulty > 0 {
+		profit.DailyTrtl = input.Hashrate * 86400 / float64(input.Difficulty) * blockReward
+	}
+	profit.DailyRevenue = profit.DailyTrtl * trtlPrice
+	profit.DailyPowerCost = input.Watts / 1000 * 24 * input.PowerCost
+	profit.DailyProfit = profit.DailyRevenue - profit.DailyPowerCost
+	return profit
+}
@@ -0,0 +1,22 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateMiningProfit(t *testing.T) {
+	input := MiningInput{
+		Hashrate:   5000,
+		Watts:      250,
+		PowerCost:  0.12,
+		Fiat:       "USD",
+		Difficulty: 432000000,
+	}
+	profit := CalculateMiningProfit(input, 29000, 0.0001)
+	assert.InDelta(t, 29000, profit.DailyTrtl, 1e-9)
+	assert.InDelta(t, 2.9, profit.DailyRevenue, 1e-9)
+	assert.InDelta(t, 0.72, profit.DailyPowerCost, 1e-9)
+	assert.InDelta(t, 2.18, profit.DailyProfit, 1e-9)
+}
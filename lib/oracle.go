@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrOracleDisabled is returned when an attestation is asked for without a signing key configured
+var ErrOracleDisabled = errors.New("oracle mode is not enabled")
+
+// ErrAttestationInvalid is returned when an attestation's signature doesn't match its payload
+var ErrAttestationInvalid = errors.New("attestation signature is invalid")
+
+// Attestation is a signed statement that this service saw a price at a point in time
+type Attestation struct {
+	Pair      string   `json:"pair"`
+	Price     string   `json:"price"`
+	Timestamp int64    `json:"timestamp"`
+	Sources   []string `json:"sources"`
+	Signature string   `json:"signature"`
+}
+
+var (
+	oracleKey     ed25519.PrivateKey
+	oracleKeyErr  error
+	oracleKeyOnce sync.Once
+)
+
+// OracleKey loads the Ed25519 signing key from $ORACLE_PRIVATE_KEY (a base64 32 byte seed)
+func OracleKey() (ed25519.PrivateKey, error) {
+	oracleKeyOnce.Do(func() {
+		encoded := os.Getenv("ORACLE_PRIVATE_KEY")
+		if encoded == "" {
+			oracleKeyErr = ErrOracleDisabled
+			return
+		}
+		seed, decodeErr := base64.StdEncoding.DecodeString(encoded)
+		if decodeErr != nil || len(seed) != ed25519.SeedSize {
+			oracleKeyErr = errors.New("ORACLE_PRIVATE_KEY must be a base64 encoded 32 byte seed")
+			Logger(context.Background()).Error("oracle key is invalid", "error", oracleKeyErr.Error())
+			return
+		}
+		oracleKey = ed25519.NewKeyFromSeed(seed)
+	})
+	return oracleKey, oracleKeyErr
+}
+
+// OracleEnabled reports whether prices should be signed
+func OracleEnabled() bool {
+	_, err := OracleKey()
+	return err == nil
+}
+
+// OraclePublicKey is the key downstream services use with VerifyAttestation
+func OraclePublicKey() (ed25519.PublicKey, error) {
+	key, err := OracleKey()
+	if err != nil {
+		return nil, err
+	}
+	return key.Public().(ed25519.PublicKey), nil
+}
+
+// CanonicalPayload is the exact byte string that gets signed for an attestation
+func (attestation Attestation) CanonicalPayload() []byte {
+	return []byte(fmt.Sprintf("turtle-utils/price-attestation/v1\npair=%s\nprice=%s\ntimestamp=%d\nsources=%s\n",
+		attestation.Pair,
+		attestation.Price,
+		attestation.Timestamp,
+		strings.Join(attestation.Sources, ",")))
+}
+
+// SignAttestation signs a price for a pair with the given key
+func SignAttestation(key ed25519.PrivateKey, pair string, price float64, at time.Time, sources []string) Attestation {
+	attestation := Attestation{
+		Pair:      pair,
+		Price:     strconv.FormatFloat(price, 'f', -1, 64),
+		Timestamp: at.Unix(),
+		Sources:   sources,
+	}
+	signature := ed25519.Sign(key, attestation.CanonicalPayload())
+	attestation.Signature = base64.StdEncoding.EncodeToString(signature)
+	return attestation
+}
+
+// VerifyAttestation checks that an attestation was signed by the holder of publicKey and hasn't been altered
+func VerifyAttestation(publicKey ed25519.PublicKey, attestation Attestation) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return ErrAttestationInvalid
+	}
+	signature, decodeErr := base64.StdEncoding.DecodeString(attestation.Signature)
+	if decodeErr != nil {
+		return ErrAttestationInvalid
+	}
+	if !ed25519.Verify(publicKey, attestation.CanonicalPayload(), signature) {
+		return ErrAttestationInvalid
+	}
+	return nil
+}
+
+// AttestPrice signs the TRTL-BTC and TRTL-USD prices in a price hash
+// They're stamped with when the price was pulled, a cached price doesn't get to claim it's fresh, and name only the
+// sources that were averaged for it
+func AttestPrice(price CurrentPrice) (attestations []Attestation, err error) {
+	key, keyErr := OracleKey()
+	if keyErr != nil {
+		return attestations, keyErr
+	}
+
+	at := price.fetchedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	usdSources := append(append([]string{}, price.sources...), "coinbase:BTC-USD")
+	attestations = []Attestation{
+		SignAttestation(key, "TRTL-BTC", price.btcPrice, at, price.sources),
+		SignAttestation(key, "TRTL-USD", price.usdPrice, at, usdSources),
+	}
+	return attestations, nil
+}
@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerifyAttestation(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	publicKey := key.Public().(ed25519.PublicKey)
+	at := time.Unix(1519900000, 0)
+
+	attestation := SignAttestation(key, "TRTL-BTC", 0.00000016, at, []string{"tradeogre:BTC-TRTL"})
+	assert.Equal(t, "0.00000016", attestation.Price)
+	assert.Equal(t, "turtle-utils/price-attestation/v1\npair=TRTL-BTC\nprice=0.00000016\ntimestamp=1519900000\nsources=tradeogre:BTC-TRTL\n",
+		string(attestation.CanonicalPayload()))
+	assert.Nil(t, VerifyAttestation(publicKey, attestation))
+
+	attestation.Price = "0.00000080"
+	assert.Equal(t, ErrAttestationInvalid, VerifyAttestation(publicKey, attestation))
+}
+
+func TestVerifyAttestationRejectsABadKey(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	attestation := SignAttestation(key, "TRTL-BTC", 0.00000016, time.Unix(1519900000, 0), []string{"tradeogre:BTC-TRTL"})
+
+	assert.Equal(t, ErrAttestationInvalid, VerifyAttestation(nil, attestation))
+	assert.Equal(t, ErrAttestationInvalid, VerifyAttestation(ed25519.PublicKey("short"), attestation))
+}
+
+func TestCachedPriceKeepsWhenItWasFetched(t *testing.T) {
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.TrtlCacheTTL = time.Minute
+	settings.FiatCacheTTL = time.Minute
+	Configure(settings)
+
+	trtlAt, usdAt := time.Now().Add(-20*time.Second), time.Now().Add(-10*time.Second)
+	prices.setQuote("BTC-TRTL", btcQuote{price: 0.00000016, fetchedAt: trtlAt})
+	prices.set("BTC-USD", 10000, usdAt)
+
+	price, err := GetPriceHash(false)
+	assert.Nil(t, err)
+	assert.True(t, trtlAt.Equal(price.FetchedAt()))
+
+	_, fetchedAt, _, err := GetTrtlPriceFetchedContext(context.Background(), "USD", false)
+	assert.Nil(t, err)
+	assert.True(t, trtlAt.Equal(fetchedAt))
+}
+
+func TestAttestPriceSignsOnlyTheAveragedSources(t *testing.T) {
+	os.Setenv("ORACLE_PRIVATE_KEY", base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize)))
+	defer os.Unsetenv("ORACLE_PRIVATE_KEY")
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.TrtlCacheTTL = time.Minute
+	settings.FiatCacheTTL = time.Minute
+	settings.Sources = append(settings.Sources, Source{Name: "down", Kind: "tradeogre", Pair: "BTC-TRTL", Weight: 1})
+	Configure(settings)
+
+	prices.setQuote("BTC-TRTL", btcQuote{price: 0.00000016, fetchedAt: time.Now(), sources: []string{"tradeogre:BTC-TRTL"}})
+	prices.set("BTC-USD", 10000, time.Now())
+	price, err := GetPriceHash(false)
+	assert.Nil(t, err)
+
+	attestations, err := AttestPrice(price)
+	assert.Nil(t, err)
+	if assert.Len(t, attestations, 2) {
+		assert.Equal(t, []string{"tradeogre:BTC-TRTL"}, attestations[0].Sources)
+		assert.Equal(t, []string{"tradeogre:BTC-TRTL", "coinbase:BTC-USD"}, attestations[1].Sources)
+	}
+
+	quote, err := NewQuote(false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tradeogre:BTC-TRTL", "coinbase:BTC-USD"}, quote.Sources)
+}
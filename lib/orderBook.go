@@ -0,0 +1,206 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// OrderBookLevel is the total quantity of TRTL resting at one price
+type OrderBookLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// OrderBook holds the bids (best first, highest price) and asks (best first, lowest price) for BTC-TRTL
+type OrderBook struct {
+	Bids []OrderBookLevel `json:"bids"`
+	Asks []OrderBookLevel `json:"asks"`
+}
+
+// Fill is the result of walking the order book for an amount of TRTL
+type Fill struct {
+	Side            string  `json:"side"`
+	Requested       float64 `json:"requested"`
+	Filled          float64 `json:"filled"`
+	Unfilled        float64 `json:"unfilled"`
+	BtcTotal        float64 `json:"btcTotal"`
+	UsdTotal        float64 `json:"usdTotal"`
+	AveragePrice    float64 `json:"averagePrice"`
+	SpotPrice       float64 `json:"spotPrice"`
+	SlippagePercent float64 `json:"slippagePercent"`
+}
+
+// PullTradeOgreOrderBook hits the first configured tradeogre BTC-TRTL source to get its order book
+func PullTradeOgreOrderBook() (book OrderBook, err error) {
+	return PullTradeOgreOrderBookContext(context.Background())
+}
+
+// PullTradeOgreOrderBookContext is PullTradeOgreOrderBook that gives up when ctx is done
+func PullTradeOgreOrderBookContext(ctx context.Context) (book OrderBook, err error) {
+	var source *Source
+	for _, btcSource := range btcSources() {
+		if btcSource.Kind == "tradeogre" {
+			source = &btcSource
+			break
+		}
+	}
+	if source == nil {
+		return book, errors.New("no tradeogre BTC-TRTL source is configured")
+	}
+	return pullTradeOgreOrderBook(ctx, *source)
+}
+
+// pullTradeOgreOrderBook hits a tradeogre source's API to get its order book
+func pullTradeOgreOrderBook(ctx context.Context, source Source) (book OrderBook, err error) {
+	ordersURL := fmt.Sprintf("%s/orders/%s", source.URL, source.Pair)
+
+	client := http.Client{
+		Timeout: CurrentSettings().ExchangeTimeout,
+	}
+
+	ordersReq, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, ordersURL, nil)
+	if reqErr != nil {
+		return book, reqErr
+	}
+
+	body, fetchErr := fetchUpstream(ctx, &client, source.Name, ordersReq)
+	if fetchErr != nil {
+		return book, fetchErr
+	}
+
+	return parseTradeOgreOrderBook(body)
+}
+
+// parseTradeOgreOrderBook reads tradeogre's {"buy":{price:quantity},"sell":{price:quantity}} format
+func parseTradeOgreOrderBook(body []byte) (book OrderBook, err error) {
+	type tradeOgreOrders struct {
+		Error string            `json:"error"`
+		Buy   map[string]string `json:"buy"`
+		Sell  map[string]string `json:"sell"`
+	}
+
+	orders := tradeOgreOrders{}
+	jsonErr := json.Unmarshal(body, &orders)
+	if jsonErr != nil {
+		return book, jsonErr
+	}
+	if orders.Error != "" {
+		return book, errors.New(orders.Error)
+	}
+
+	book.Bids, err = parseOrderBookLevels(orders.Buy)
+	if err != nil {
+		return book, err
+	}
+	book.Asks, err = parseOrderBookLevels(orders.Sell)
+	if err != nil {
+		return book, err
+	}
+
+	sort.Slice(book.Bids, func(i, j int) bool { return book.Bids[i].Price > book.Bids[j].Price })
+	sort.Slice(book.Asks, func(i, j int) bool { return book.Asks[i].Price < book.Asks[j].Price })
+	return book, nil
+}
+
+func parseOrderBookLevels(orders map[string]string) (levels []OrderBookLevel, err error) {
+	for price, quantity := range orders {
+		level := OrderBookLevel{}
+		level.Price, err = strconv.ParseFloat(price, 64)
+		if err != nil {
+			return levels, err
+		}
+		level.Quantity, err = strconv.ParseFloat(quantity, 64)
+		if err != nil {
+			return levels, err
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// Fill walks the bids (side "sell") or asks (side "buy") until trtl has been filled or the book runs out
+func (book OrderBook) Fill(side string, trtl float64, spotPrice float64) (fill Fill, err error) {
+	if trtl <= 0 || math.IsInf(trtl, 0) || math.IsNaN(trtl) {
+		return fill, errors.Errorf("%s TRTL can't be filled, the amount must be more than 0", strconv.FormatFloat(trtl, 'f', -1, 64))
+	}
+	var levels []OrderBookLevel
+	switch side {
+	case "sell":
+		levels = book.Bids
+	case "buy":
+		levels = book.Asks
+	default:
+		return fill, errors.Errorf("side must be buy or sell, not %q", side)
+	}
+
+	fill = Fill{
+		Side:      side,
+		Requested: trtl,
+		SpotPrice: spotPrice,
+	}
+	remaining := trtl
+	for _, level := range levels {
+		if remaining <= 0 {
+			break
+		}
+		taken := level.Quantity
+		if taken > remaining {
+			taken = remaining
+		}
+		fill.Filled += taken
+		fill.BtcTotal += taken * level.Price
+		remaining -= taken
+	}
+	fill.Unfilled = remaining
+
+	if fill.Filled > 0 {
+		fill.AveragePrice = fill.BtcTotal / fill.Filled
+	}
+	if fill.Filled > 0 && spotPrice > 0 {
+		if side == "sell" {
+			fill.SlippagePercent = (spotPrice - fill.AveragePrice) / spotPrice * 100
+		} else {
+			fill.SlippagePercent = (fill.AveragePrice - spotPrice) / spotPrice * 100
+		}
+	}
+	return fill, nil
+}
+
+// ConvertTurtleOrderBook works out what trading trtl against the tradeogre order book would actually give
+func ConvertTurtleOrderBook(trtl int64, side string, forceCheck bool) (fill Fill, err error) {
+	return ConvertTurtleOrderBookContext(context.Background(), trtl, side, forceCheck)
+}
+
+// ConvertTurtleOrderBookContext is ConvertTurtleOrderBook that gives up when ctx is done
+func ConvertTurtleOrderBookContext(ctx context.Context, trtl int64, side string, forceCheck bool) (fill Fill, err error) {
+	exchangeCtx, cancel := budget(ctx, exchangeShare())
+	defer cancel()
+	book, bookErr := PullTradeOgreOrderBookContext(exchangeCtx)
+	if bookErr != nil {
+		return fill, errors.Wrap(bookErr, "Problem getting the order book")
+	}
+
+	spotPrice, getBtcPriceErr := GetTrtlToBtcPriceContext(exchangeCtx, forceCheck)
+	if getBtcPriceErr != nil {
+		return fill, errors.Wrap(getBtcPriceErr, "Problem getting BTC Price")
+	}
+
+	fill, err = book.Fill(side, float64(trtl), spotPrice)
+	if err != nil {
+		return fill, err
+	}
+
+	btcToUsd, getUsdBtcErr := GetBtcToUsdPriceContext(ctx, forceCheck)
+	if getUsdBtcErr != nil {
+		return fill, errors.Wrap(getUsdBtcErr, "Problem getting USD Price")
+	}
+	fill.UsdTotal = fill.BtcTotal * btcToUsd
+	return fill, nil
+}
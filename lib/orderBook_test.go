@@ -0,0 +1,65 @@
+package lib
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadOrderBookFixture(t *testing.T, name string) (OrderBook, error) {
+	body, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseTradeOgreOrderBook(body)
+}
+
+func TestParseTradeOgreOrderBook(t *testing.T) {
+	book, err := loadOrderBookFixture(t, "tradeogre_orders_btc_trtl.json")
+	assert.Nil(t, err)
+	assert.Len(t, book.Bids, 4)
+	assert.Len(t, book.Asks, 4)
+	assert.Equal(t, 0.00000004, book.Bids[0].Price)
+	assert.Equal(t, 0.00000005, book.Asks[0].Price)
+
+	_, err = loadOrderBookFixture(t, "tradeogre_orders_error.json")
+	assert.EqualError(t, err, "Market not found")
+}
+
+func TestOrderBookFillSell(t *testing.T) {
+	book, err := loadOrderBookFixture(t, "tradeogre_orders_btc_trtl.json")
+	assert.Nil(t, err)
+
+	fill, err := book.Fill("sell", 10000000, 0.00000005)
+	assert.Nil(t, err)
+	assert.Equal(t, 10000000.0, fill.Filled)
+	assert.Equal(t, 0.0, fill.Unfilled)
+	assert.InDelta(t, 0.335, fill.BtcTotal, 1e-12)
+	assert.InDelta(t, 0.0000000335, fill.AveragePrice, 1e-18)
+	assert.InDelta(t, 33.0, fill.SlippagePercent, 1e-9)
+
+	fill, err = book.Fill("sell", 400000000, 0.00000005)
+	assert.Nil(t, err)
+	assert.Equal(t, 355500000.0, fill.Filled)
+	assert.Equal(t, 44500000.0, fill.Unfilled)
+}
+
+func TestOrderBookFillBuy(t *testing.T) {
+	book, err := loadOrderBookFixture(t, "tradeogre_orders_btc_trtl.json")
+	assert.Nil(t, err)
+
+	fill, err := book.Fill("buy", 20000000, 0.00000005)
+	assert.Nil(t, err)
+	assert.Equal(t, 20000000.0, fill.Filled)
+	assert.InDelta(t, 1.17, fill.BtcTotal, 1e-12)
+	assert.InDelta(t, 0.0000000585, fill.AveragePrice, 1e-18)
+	assert.InDelta(t, 17.0, fill.SlippagePercent, 1e-9)
+
+	_, err = book.Fill("hold", 1, 0.00000005)
+	assert.NotNil(t, err)
+	_, err = book.Fill("buy", 0, 0.00000005)
+	assert.NotNil(t, err)
+	_, err = book.Fill("sell", -100, 0.00000005)
+	assert.NotNil(t, err)
+}
@@ -2,6 +2,7 @@ package lib
 
 import (
 	"fmt"
+	"time"
 )
 
 // CurrentPrice is the return object containing Turtle's value in BTC and USD
@@ -10,6 +11,9 @@ type CurrentPrice struct {
 	CurrentBtcPrice string `json:"btcPrice"`
 	usdPrice        float64
 	btcPrice        float64
+	fetchedAt       time.Time
+	sources         []string
+	stale           bool
 }
 
 // SetCurrentPrices is kind of a hacky way to set the strings in the struct so I don't have to mess with a custom map right now
@@ -18,3 +22,23 @@ func (price CurrentPrice) SetCurrentPrices() CurrentPrice {
 	price.CurrentBtcPrice = fmt.Sprintf("Ƀ%.8f", price.btcPrice)
 	return price
 }
+
+// UsdPrice is the USD price as a number
+func (price CurrentPrice) UsdPrice() float64 {
+	return price.usdPrice
+}
+
+// BtcPrice is the BTC price as a number
+func (price CurrentPrice) BtcPrice() float64 {
+	return price.btcPrice
+}
+
+// FetchedAt is when the oldest of the prices was pulled from upstream, zero when it wasn't pulled
+func (price CurrentPrice) FetchedAt() time.Time {
+	return price.fetchedAt
+}
+
+// Stale is whether every fresh TRTL quote was quarantined and the last accepted one was used instead
+func (price CurrentPrice) Stale() bool {
+	return price.stale
+}
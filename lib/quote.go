@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// QuoteTTL is how long a quote can be used for conversions after it is issued
+var QuoteTTL = 60 * time.Second
+
+// ErrQuoteInvalid is returned when a quote ID is malformed or its signature doesn't match
+var ErrQuoteInvalid = errors.New("quote is invalid")
+
+// ErrQuoteExpired is returned when a quote has a valid signature but is past its expiry
+var ErrQuoteExpired = errors.New("quote has expired")
+
+// Quote is a signed snapshot of the rates used for a conversion so the same numbers can be reused later
+type Quote struct {
+	ID        string    `json:"id"`
+	TrtlToBtc float64   `json:"trtlToBtc"`
+	BtcToUsd  float64   `json:"btcToUsd"`
+	Sources   []string  `json:"sources"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+var (
+	quoteSecret     []byte
+	quoteSecretOnce sync.Once
+)
+
+// QuoteSecret returns the HMAC key used to sign quotes
+// It comes from $QUOTE_SECRET, otherwise a random key is made that only lasts for this process
+func QuoteSecret() []byte {
+	quoteSecretOnce.Do(func() {
+		if secret := os.Getenv("QUOTE_SECRET"); secret != "" {
+			quoteSecret = []byte(secret)
+			return
+		}
+		Logger(context.Background()).Warn("QUOTE_SECRET is not set, quotes will not survive a restart")
+		quoteSecret = make([]byte, 32)
+		if _, err := rand.Read(quoteSecret); err != nil {
+			Logger(context.Background()).Error("Could not generate a quote secret", "error", err.Error())
+			os.Exit(1)
+		}
+	})
+	return quoteSecret
+}
+
+// NewQuote pulls the current rates and signs them into a quote
+func NewQuote(forceCheck bool) (quote Quote, err error) {
+	return NewQuoteContext(context.Background(), forceCheck)
+}
+
+// NewQuoteContext is NewQuote that gives up when ctx is done
+func NewQuoteContext(ctx context.Context, forceCheck bool) (quote Quote, err error) {
+	btcCtx, cancel := budget(ctx, exchangeShare())
+	btc, getBtcPriceErr := getTrtlToBtcQuote(btcCtx, forceCheck)
+	cancel()
+	if getBtcPriceErr != nil {
+		return quote, errors.Wrap(getBtcPriceErr, "Problem getting BTC Price")
+	}
+
+	btcToUsd, getUsdBtcErr := GetBtcToUsdPriceContext(ctx, forceCheck)
+	if getUsdBtcErr != nil {
+		return quote, errors.Wrap(getUsdBtcErr, "Problem getting USD Price")
+	}
+
+	now := time.Now().UTC()
+	quote = Quote{
+		TrtlToBtc: btc.price,
+		BtcToUsd:  btcToUsd,
+		Sources:   append(append([]string{}, btc.sources...), "coinbase:BTC-USD"),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(QuoteTTL),
+	}
+	return SignQuote(quote, QuoteSecret())
+}
+
+// SignQuote sets the quote ID to the encoded quote followed by its HMAC
+func SignQuote(quote Quote, secret []byte) (Quote, error) {
+	quote.ID = ""
+	payload, jsonErr := json.Marshal(quote)
+	if jsonErr != nil {
+		return quote, jsonErr
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	quote.ID = encoded + "." + base64.RawURLEncoding.EncodeToString(quoteMAC(encoded, secret))
+	return quote, nil
+}
+
+// ParseQuote checks the signature and expiry of a quote ID and gives back the quote
+func ParseQuote(id string, secret []byte) (quote Quote, err error) {
+	return parseQuoteAt(id, secret, time.Now())
+}
+
+func parseQuoteAt(id string, secret []byte, now time.Time) (quote Quote, err error) {
+	parts := strings.Split(id, ".")
+	if len(parts) != 2 {
+		return quote, ErrQuoteInvalid
+	}
+
+	mac, macErr := base64.RawURLEncoding.DecodeString(parts[1])
+	if macErr != nil || !hmac.Equal(mac, quoteMAC(parts[0], secret)) {
+		return quote, ErrQuoteInvalid
+	}
+
+	payload, decodeErr := base64.RawURLEncoding.DecodeString(parts[0])
+	if decodeErr != nil {
+		return quote, ErrQuoteInvalid
+	}
+	if jsonErr := json.Unmarshal(payload, &quote); jsonErr != nil {
+		return quote, ErrQuoteInvalid
+	}
+	quote.ID = id
+
+	if !now.Before(quote.ExpiresAt) {
+		return quote, ErrQuoteExpired
+	}
+	return quote, nil
+}
+
+func quoteMAC(encoded string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// CurrentPrice turns the quoted rates into the price of a single turtle
+func (quote Quote) CurrentPrice() CurrentPrice {
+	price := CurrentPrice{
+		btcPrice: quote.TrtlToBtc,
+		usdPrice: quote.TrtlToBtc * quote.BtcToUsd,
+	}
+	return price.SetCurrentPrices()
+}
+
+// ConvertTurtleQuote converts turtle coins using the rates locked in by a quote
+func ConvertTurtleQuote(quote Quote, trtl int64) (priceHash CurrentPrice, err error) {
+	return ConvertTurtlePrice(quote.CurrentPrice(), trtl)
+}
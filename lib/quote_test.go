@@ -0,0 +1,47 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndParseQuote(t *testing.T) {
+	secret := []byte("test-secret")
+	issued := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	quote, err := SignQuote(Quote{
+		TrtlToBtc: 0.00000016,
+		BtcToUsd:  10000,
+		Sources:   []string{"tradeogre:BTC-TRTL", "coinbase:BTC-USD"},
+		IssuedAt:  issued,
+		ExpiresAt: issued.Add(time.Minute),
+	}, secret)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, quote.ID)
+
+	parsed, err := parseQuoteAt(quote.ID, secret, issued.Add(30*time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, quote, parsed)
+
+	converted, err := ConvertTurtleQuote(parsed, 500)
+	assert.Nil(t, err)
+	assert.Equal(t, "$0.80000000", converted.CurrentUsdPrice)
+}
+
+func TestParseQuoteRejectsTampering(t *testing.T) {
+	secret := []byte("test-secret")
+	issued := time.Now().UTC()
+	quote, err := SignQuote(Quote{TrtlToBtc: 0.00000016, BtcToUsd: 10000, IssuedAt: issued, ExpiresAt: issued.Add(time.Minute)}, secret)
+	assert.Nil(t, err)
+
+	forged, err := SignQuote(Quote{TrtlToBtc: 0.001, BtcToUsd: 10000, IssuedAt: issued, ExpiresAt: issued.Add(time.Minute)}, []byte("wrong"))
+	assert.Nil(t, err)
+
+	_, err = ParseQuote(forged.ID, secret)
+	assert.Equal(t, ErrQuoteInvalid, err)
+	_, err = ParseQuote("not-a-quote", secret)
+	assert.Equal(t, ErrQuoteInvalid, err)
+	_, err = parseQuoteAt(quote.ID, secret, issued.Add(time.Minute))
+	assert.Equal(t, ErrQuoteExpired, err)
+}
@@ -0,0 +1,252 @@
+package lib
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Route preferences, what FindRoute picks between paths on
+const (
+	RouteBest   = "best"
+	RouteLiquid = "liquid"
+)
+
+// MaxRouteHops is how many markets a route can go through
+const MaxRouteHops = 4
+
+// ErrNoRoute is returned when the market graph has no path between two assets
+var ErrNoRoute = errors.New("no route between those assets")
+
+// Edge is a market that turns one From into Rate of To
+// Liquidity is how much From it traded in the last day, 0 when the source doesn't say
+type Edge struct {
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Rate      float64 `json:"rate"`
+	Source    string  `json:"source"`
+	Liquidity float64 `json:"liquidity,omitempty"`
+}
+
+// MarketGraph is every conversion the configured sources can make, by the asset converted from
+type MarketGraph struct {
+	edges map[string][]Edge
+}
+
+// Route is a path through the market graph and what one of the starting asset is worth at the end of it
+// Liquidity is the most of the starting asset the thinnest market can take, nil when none of the markets say
+type Route struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Rate      float64  `json:"rate"`
+	Liquidity *float64 `json:"liquidity,omitempty"`
+	Hops      []Edge   `json:"hops"`
+}
+
+// NewMarketGraph builds a graph out of edges, ignoring any without a usable rate
+func NewMarketGraph(edges []Edge) *MarketGraph {
+	graph := &MarketGraph{edges: map[string][]Edge{}}
+	for _, edge := range edges {
+		if edge.Rate <= 0 || math.IsInf(edge.Rate, 0) || math.IsNaN(edge.Rate) || edge.From == edge.To {
+			continue
+		}
+		edge.From, edge.To = strings.ToUpper(edge.From), strings.ToUpper(edge.To)
+		graph.edges[edge.From] = append(graph.edges[edge.From], edge)
+	}
+	return graph
+}
+
+// Assets is every asset in the graph, sorted
+func (graph *MarketGraph) Assets() (assets []string) {
+	seen := map[string]bool{}
+	for from, edges := range graph.edges {
+		seen[from] = true
+		for _, edge := range edges {
+			seen[edge.To] = true
+		}
+	}
+	for asset := range seen {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// FindRoute finds the path from one asset to another through at most maxHops markets
+// best picks the path that ends with the most, liquid the one whose thinnest market can take the most
+// Ties go to the shorter path, then the other preference
+func (graph *MarketGraph) FindRoute(from string, to string, prefer string, maxHops int) (route Route, err error) {
+	if prefer != RouteBest && prefer != RouteLiquid {
+		return route, errors.Errorf("route must be %s or %s, not %s", RouteBest, RouteLiquid, prefer)
+	}
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return route, errors.Errorf("%s is already %s", from, to)
+	}
+
+	found := false
+	visited := map[string]bool{from: true}
+	var hops []Edge
+	var walk func(asset string, rate float64, liquidity float64)
+	walk = func(asset string, rate float64, liquidity float64) {
+		if asset == to {
+			candidate := Route{From: from, To: to, Rate: rate, Hops: append([]Edge{}, hops...)}
+			if !math.IsInf(liquidity, 1) {
+				candidate.Liquidity = &liquidity
+			}
+			if !found || betterRoute(candidate, route, prefer) {
+				route, found = candidate, true
+			}
+			return
+		}
+		if len(hops) == maxHops {
+			return
+		}
+		for _, edge := range graph.edges[asset] {
+			if visited[edge.To] {
+				continue
+			}
+			// the edge's liquidity is in its own From, rate turns the starting asset into that
+			edgeLiquidity := liquidity
+			if edge.Liquidity > 0 {
+				edgeLiquidity = math.Min(liquidity, edge.Liquidity/rate)
+			}
+			visited[edge.To] = true
+			hops = append(hops, edge)
+			walk(edge.To, rate*edge.Rate, edgeLiquidity)
+			hops = hops[:len(hops)-1]
+			visited[edge.To] = false
+		}
+	}
+	walk(from, 1, math.Inf(1))
+
+	if !found {
+		return route, errors.Wrapf(ErrNoRoute, "%s to %s in %d hops", from, to, maxHops)
+	}
+	return route, nil
+}
+
+// betterRoute is whether candidate beats current for the preference
+func betterRoute(candidate Route, current Route, prefer string) bool {
+	liquidity := func(route Route) float64 {
+		if route.Liquidity == nil {
+			return math.Inf(1)
+		}
+		return *route.Liquidity
+	}
+
+	const epsilon = 1e-12
+	if prefer == RouteLiquid {
+		if a, b := liquidity(candidate), liquidity(current); math.Abs(a-b) > epsilon*math.Max(1, math.Abs(b)) || math.IsInf(a, 1) != math.IsInf(b, 1) {
+			return a > b
+		}
+	} else if math.Abs(candidate.Rate-current.Rate) > epsilon*current.Rate {
+		return candidate.Rate > current.Rate
+	}
+	if len(candidate.Hops) != len(current.Hops) {
+		return len(candidate.Hops) < len(current.Hops)
+	}
+	if prefer == RouteLiquid {
+		return candidate.Rate > current.Rate
+	}
+	return liquidity(candidate) > liquidity(current)
+}
+
+// GetMarketGraph pulls every configured source and the BTC to fiat prices into a market graph
+func GetMarketGraph(forceCheck bool) (*MarketGraph, error) {
+	return GetMarketGraphContext(context.Background(), forceCheck)
+}
+
+// GetMarketGraphContext is GetMarketGraph that gives up when ctx is done
+// A source that fails is left out of the graph, it's only an error when nothing works
+// Markets pulled within the TRTL cache TTL aren't pulled again unless forceCheck is set
+func GetMarketGraphContext(ctx context.Context, forceCheck bool) (graph *MarketGraph, err error) {
+	var edges []Edge
+	var markets []MarketStats
+	var sourceErrs []string
+	for _, source := range CurrentSettings().Sources {
+		sourceEdges, stats, sourceErr := getSourceMarket(ctx, source, forceCheck)
+		if sourceErr != nil {
+			Logger(ctx).Warn("Problem pulling a market for the graph, leaving it out", "source", source.Name, "error", sourceErr.Error())
+			sourceErrs = append(sourceErrs, source.Name+": "+sourceErr.Error())
+			continue
+		}
+		edges = append(edges, sourceEdges...)
+		if stats != nil {
+			markets = append(markets, *stats)
+		}
+	}
+	// a fat-finger trade isn't a route, so the BTC-TRTL markets are screened like the published price
+	for _, stats := range screenMarkets(ctx, markets) {
+		assets := strings.SplitN(strings.ToUpper(stats.Pair), "-", 2)
+		edges = append(edges, tradeOgreEdges(stats.Source, assets[0], assets[1], stats)...)
+	}
+
+	for _, fiat := range Fiats() {
+		btcToFiat, fiatErr := GetBtcToFiatPriceContext(ctx, fiat, forceCheck)
+		if fiatErr != nil {
+			Logger(ctx).Warn("Problem getting a BTC price for the graph, leaving it out", "fiat", fiat, "error", fiatErr.Error())
+			sourceErrs = append(sourceErrs, "BTC-"+fiat+": "+fiatErr.Error())
+			continue
+		}
+		edges = append(edges, spotEdges("coinbase", "BTC", fiat, btcToFiat)...)
+	}
+
+	if len(edges) == 0 {
+		return graph, errors.Errorf("Problem building the market graph: %s", strings.Join(sourceErrs, "; "))
+	}
+	return NewMarketGraph(edges), nil
+}
+
+// getSourceMarket is a coinbase source's two edges, one each way, or a tradeogre source's stats to be screened
+// before they're turned into edges
+func getSourceMarket(ctx context.Context, source Source, forceCheck bool) (edges []Edge, stats *MarketStats, err error) {
+	assets := strings.SplitN(strings.ToUpper(source.Pair), "-", 2)
+	if len(assets) != 2 {
+		return edges, stats, errors.Errorf("pair %q should look like BTC-TRTL", source.Pair)
+	}
+
+	if source.Kind == "coinbase" {
+		spot, spotErr := getSourcePrice(ctx, source, forceCheck)
+		if spotErr != nil {
+			return edges, stats, spotErr
+		}
+		return spotEdges(source.Name, assets[0], assets[1], spot), stats, nil
+	}
+
+	market, statsErr := getMarketStats(ctx, source, forceCheck)
+	if statsErr != nil {
+		return edges, stats, statsErr
+	}
+	return edges, &market, nil
+}
+
+// tradeOgreEdges turns a market priced in market into asset edges, selling at the bid and buying at the ask when
+// there's a book. Volume is in the market currency
+func tradeOgreEdges(source string, market string, asset string, stats MarketStats) []Edge {
+	sell, buy := stats.Last, stats.Last
+	if stats.Bid != nil && stats.Ask != nil && *stats.Bid > 0 && *stats.Ask > 0 {
+		sell, buy = *stats.Bid, *stats.Ask
+	}
+	if sell <= 0 || buy <= 0 {
+		return nil
+	}
+	return []Edge{
+		{From: asset, To: market, Rate: sell, Source: source, Liquidity: stats.Volume / ((sell + buy) / 2)},
+		{From: market, To: asset, Rate: 1 / buy, Source: source, Liquidity: stats.Volume},
+	}
+}
+
+// spotEdges turns a spot price of base in quote into edges with no known liquidity
+func spotEdges(source string, base string, quote string, spot float64) []Edge {
+	if spot <= 0 {
+		return nil
+	}
+	return []Edge{
+		{From: base, To: quote, Rate: spot, Source: source},
+		{From: quote, To: base, Rate: 1 / spot, Source: source},
+	}
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func testGraph() *MarketGraph {
+	var edges []Edge
+	// TRTL at 50 sats on a busy BTC market, and a thin LTC market that pays a little more
+	edges = append(edges, spotEdges("tradeogre", "TRTL", "BTC", 0.0000005)...)
+	edges[0].Liquidity, edges[1].Liquidity = 1000000000, 500
+	edges = append(edges, Edge{From: "TRTL", To: "LTC", Rate: 0.00004, Source: "tradeogre-ltc", Liquidity: 1000000})
+	edges = append(edges, spotEdges("coinbase", "LTC", "USD", 100)...)
+	edges = append(edges, spotEdges("coinbase", "BTC", "USD", 7000)...)
+	return NewMarketGraph(edges)
+}
+
+func TestFindRouteBest(t *testing.T) {
+	route, err := testGraph().FindRoute("trtl", "usd", RouteBest, MaxRouteHops)
+	assert.Nil(t, err)
+	assert.Equal(t, "TRTL", route.From)
+	assert.Equal(t, "USD", route.To)
+	assert.InDelta(t, 0.004, route.Rate, 1e-12)
+	assert.Len(t, route.Hops, 2)
+	assert.Equal(t, "LTC", route.Hops[0].To)
+	assert.Equal(t, 1000000.0, *route.Liquidity)
+}
+
+func TestFindRouteLiquid(t *testing.T) {
+	route, err := testGraph().FindRoute("TRTL", "USD", RouteLiquid, MaxRouteHops)
+	assert.Nil(t, err)
+	assert.InDelta(t, 0.0035, route.Rate, 1e-12)
+	assert.Equal(t, "BTC", route.Hops[0].To)
+	assert.Equal(t, 1000000000.0, *route.Liquidity)
+}
+
+func TestFindRouteLiquidityInStartingAsset(t *testing.T) {
+	// buying TRTL with USD goes through BTC, whose market can take 500 BTC, or 3.5 million USD
+	route, err := testGraph().FindRoute("USD", "TRTL", RouteBest, MaxRouteHops)
+	assert.Nil(t, err)
+	assert.Len(t, route.Hops, 2)
+	assert.InDelta(t, 3500000.0, *route.Liquidity, 1e-6)
+}
+
+func TestFindRouteMissing(t *testing.T) {
+	_, err := testGraph().FindRoute("TRTL", "EUR", RouteBest, MaxRouteHops)
+	assert.Equal(t, ErrNoRoute, errors.Cause(err))
+
+	_, err = testGraph().FindRoute("LTC", "BTC", RouteBest, 1)
+	assert.NotNil(t, err)
+
+	_, err = testGraph().FindRoute("TRTL", "USD", "cheapest", MaxRouteHops)
+	assert.NotNil(t, err)
+}
+
+func TestTradeOgreEdges(t *testing.T) {
+	bid, ask := 0.00000004, 0.00000006
+	edges := tradeOgreEdges("tradeogre", "BTC", "TRTL", MarketStats{Last: 0.00000005, Volume: 10, Bid: &bid, Ask: &ask})
+	assert.Len(t, edges, 2)
+	assert.Equal(t, bid, edges[0].Rate)
+	assert.InDelta(t, 200000000.0, edges[0].Liquidity, 1e-3)
+	assert.InDelta(t, 1/ask, edges[1].Rate, 1e-3)
+	assert.Equal(t, 10.0, edges[1].Liquidity)
+}
+
+func TestGetMarketGraphCachesMarkets(t *testing.T) {
+	exchange := fixtureExchange(t, map[string]string{"/ticker/BTC-TRTL": "tradeogre_ticker_btc_trtl.json"})
+	defer exchange.Close()
+	pulls := 0
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pulls++
+		exchange.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+	coinbase := fixtureExchange(t, map[string]string{"/prices/BTC-USD/spot": "coinbase_spot_btc_usd.json"})
+	defer coinbase.Close()
+
+	defer Configure(DefaultSettings())
+	settings := DefaultSettings()
+	settings.FiatURL = coinbase.URL
+	settings.TrtlCacheTTL = time.Minute
+	settings.Sources = []Source{{Name: "tradeogre", Kind: "tradeogre", URL: counting.URL, Pair: "BTC-TRTL", Weight: 1}}
+	Configure(settings)
+
+	for _, forceCheck := range []bool{false, false, true} {
+		_, err := GetMarketGraph(forceCheck)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, pulls)
+}
@@ -0,0 +1,100 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source is an exchange market prices are pulled from
+// tradeogre pairs are written market first, BTC-TRTL is TRTL priced in BTC
+// coinbase pairs are written the other way round, LTC-USD is LTC priced in USD
+type Source struct {
+	Name   string
+	Kind   string // "tradeogre" or "coinbase"
+	URL    string // API base, like https://tradeogre.com/api/v1
+	Pair   string // market, like BTC-TRTL
+	Weight float64
+	Fees   FeeSchedule
+}
+
+// Settings are everything about lib that can be configured at runtime
+type Settings struct {
+	Sources         []Source
+	FiatURL         string // Coinbase API base, like https://api.coinbase.com/v2
+	Fiats           []string
+	DaemonURL       string
+	NetworkFee      float64 // TRTL
+	TrtlCacheTTL    time.Duration
+	FiatCacheTTL    time.Duration
+	ExchangeTimeout time.Duration
+	FiatTimeout     time.Duration
+	DaemonTimeout   time.Duration
+	Anomaly         AnomalySettings
+	// HistoryRetention is how long recorded prices are kept in memory, 0 keeps them all
+	HistoryRetention time.Duration
+}
+
+// DefaultSettings are what lib uses until Configure is called
+func DefaultSettings() Settings {
+	return Settings{
+		Sources: []Source{
+			{Name: "tradeogre", Kind: "tradeogre", URL: "https://tradeogre.com/api/v1", Pair: "BTC-TRTL", Weight: 1},
+		},
+		FiatURL:         "https://api.coinbase.com/v2",
+		Fiats:           []string{"USD"},
+		NetworkFee:      0.1,
+		TrtlCacheTTL:    0,
+		FiatCacheTTL:    0,
+		ExchangeTimeout: time.Second * 3,
+		FiatTimeout:     time.Second * 2,
+		DaemonTimeout:   time.Second * 3,
+		Anomaly: AnomalySettings{
+			Window:            6 * time.Hour,
+			MinSamples:        10,
+			JumpPercent:       50,
+			DivergencePercent: 25,
+			Confirmations:     3,
+			ConfirmFor:        15 * time.Minute,
+		},
+	}
+}
+
+var (
+	settingsLock sync.RWMutex
+	settings     = DefaultSettings()
+)
+
+// Configure swaps in new settings, it's safe to call while requests are running
+func Configure(newSettings Settings) {
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+	settings = newSettings
+	prices.clear()
+}
+
+// CurrentSettings returns the settings lib is running with
+func CurrentSettings() Settings {
+	settingsLock.RLock()
+	defer settingsLock.RUnlock()
+	return settings
+}
+
+// btcSources are the configured markets that price TRTL in BTC
+func btcSources() (sources []Source) {
+	for _, source := range CurrentSettings().Sources {
+		if source.Kind == "tradeogre" && strings.ToUpper(source.Pair) == "BTC-TRTL" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// sourceNames labels sources like tradeogre:BTC-TRTL for quotes and attestations
+func sourceNames(sources []Source) (names []string) {
+	for _, source := range sources {
+		names = append(names, fmt.Sprintf("%s:%s", source.Name, source.Pair))
+	}
+	return names
+}
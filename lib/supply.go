@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Supply is how much TRTL is circulating and where that number came from
+type Supply struct {
+	Height      uint64  `json:"height"`
+	Circulating float64 `json:"circulating"`
+	Max         float64 `json:"max"`
+	Source      string  `json:"source"`
+}
+
+// MarketCap is TRTL's value in one currency
+type MarketCap struct {
+	Price             float64 `json:"price"`
+	MarketCap         float64 `json:"marketCap"`
+	FullyDilutedValue float64 `json:"fullyDilutedValue"`
+}
+
+// Fiats are the configured fiat currencies
+func Fiats() []string {
+	return CurrentSettings().Fiats
+}
+
+// GetSupply asks the daemon for the generated coins, falling back to the emission formula when there isn't one
+func GetSupply() (supply Supply, err error) {
+	return GetSupplyContext(context.Background())
+}
+
+// GetSupplyContext is GetSupply that gives up on the daemon when ctx is done
+func GetSupplyContext(ctx context.Context) (supply Supply, err error) {
+	supply.Max = AtomicToTrtl(MoneySupply)
+
+	daemon, daemonErr := GetDaemon()
+	if daemonErr == nil {
+		height, coins, coinsErr := daemon.GetAlreadyGeneratedCoinsContext(ctx)
+		if coinsErr == nil {
+			supply.Height = height
+			supply.Circulating = AtomicToTrtl(coins)
+			supply.Source = "daemon"
+			return supply, nil
+		}
+		Logger(ctx).Warn("Problem getting supply from the daemon, estimating it instead", "error", coinsErr.Error())
+	}
+
+	supply.Height = EstimateHeight(time.Now())
+	supply.Circulating = AtomicToTrtl(GeneratedCoins(supply.Height))
+	supply.Source = "emission"
+	return supply, nil
+}
+
+// GetMarketCap values the circulating and max supply in BTC and every configured fiat
+func GetMarketCap(forceCheck bool) (supply Supply, caps map[string]MarketCap, err error) {
+	return GetMarketCapContext(context.Background(), forceCheck)
+}
+
+// GetMarketCapContext is GetMarketCap that gives up when ctx is done
+func GetMarketCapContext(ctx context.Context, forceCheck bool) (supply Supply, caps map[string]MarketCap, err error) {
+	supplyCtx, cancel := budget(ctx, 0.5)
+	supply, err = GetSupplyContext(supplyCtx)
+	cancel()
+	if err != nil {
+		return supply, caps, errors.Wrap(err, "Problem getting the supply")
+	}
+
+	btcCtx, cancel := budget(ctx, exchangeShare())
+	trtlToBtc, getBtcPriceErr := GetTrtlToBtcPriceContext(btcCtx, forceCheck)
+	cancel()
+	if getBtcPriceErr != nil {
+		return supply, caps, errors.Wrap(getBtcPriceErr, "Problem getting BTC Price")
+	}
+
+	caps = map[string]MarketCap{
+		"BTC": newMarketCap(supply, trtlToBtc),
+	}
+	for _, fiat := range Fiats() {
+		btcToFiat, getFiatErr := GetBtcToFiatPriceContext(ctx, fiat, forceCheck)
+		if getFiatErr != nil {
+			return supply, caps, errors.Wrapf(getFiatErr, "Problem getting BTC to %s price", fiat)
+		}
+		caps[fiat] = newMarketCap(supply, trtlToBtc*btcToFiat)
+	}
+	return supply, caps, nil
+}
+
+func newMarketCap(supply Supply, price float64) MarketCap {
+	return MarketCap{
+		Price:             price,
+		MarketCap:         supply.Circulating * price,
+		FullyDilutedValue: supply.Max * price,
+	}
+}
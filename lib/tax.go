@@ -0,0 +1,237 @@
+package lib
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cost basis methods, which holdings a disposal is taken out of
+const (
+	MethodFIFO    = "fifo"
+	MethodLIFO    = "lifo"
+	MethodAverage = "average"
+)
+
+// dust is how much TRTL can be left over from float rounding and still count as nothing
+const dust = 1e-9
+
+// Transaction is a dated movement of TRTL from a ledger
+// Buys and receives add to holdings, sells and sends take from them
+type Transaction struct {
+	Line   int       `json:"line"`
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"type"`
+	Amount float64   `json:"amount"`
+	// Value is the fiat value of the whole transaction, looked up from the price history when the ledger doesn't have it
+	// It's nil until then, a value of 0 from the ledger is kept
+	Value *float64 `json:"value"`
+}
+
+// Disposal is a sell or send with what it was worth against what it cost
+type Disposal struct {
+	Transaction
+	CostBasis float64 `json:"costBasis"`
+	Gain      float64 `json:"gain"`
+}
+
+// TaxYear is the realized gain or loss from disposals in one calendar year (UTC)
+type TaxYear struct {
+	Year      int     `json:"year"`
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"costBasis"`
+	Gain      float64 `json:"gain"`
+}
+
+// TaxReport is a ledger's realized gains per year and what is still held at the end
+type TaxReport struct {
+	Fiat      string     `json:"fiat"`
+	Method    string     `json:"method"`
+	Disposals []Disposal `json:"disposals"`
+	Years     []TaxYear  `json:"years"`
+	Held      float64    `json:"held"`
+	HeldCost  float64    `json:"heldCost"`
+}
+
+// lot is TRTL acquired together and what it cost
+type lot struct {
+	amount float64
+	cost   float64
+}
+
+// ParseLedgerCSV reads transactions from a CSV with time, type and amount columns and an optional value column
+// type is buy, sell, receive or send; the header row is required so the columns can be in any order
+func ParseLedgerCSV(r io.Reader) (transactions []Transaction, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, headerErr := reader.Read()
+	if headerErr != nil {
+		return transactions, errors.Wrap(headerErr, "Problem reading the ledger header")
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"time", "type", "amount"} {
+		if _, ok := columns[required]; !ok {
+			return transactions, errors.Errorf("the ledger needs a %s column", required)
+		}
+	}
+	valueColumn, hasValue := columns["value"]
+
+	for line := 2; ; line++ {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			return transactions, nil
+		}
+		if readErr != nil {
+			return transactions, errors.Wrap(readErr, "Problem reading the ledger")
+		}
+
+		transaction := Transaction{Line: line, Kind: strings.ToLower(strings.TrimSpace(record[columns["type"]]))}
+		if transaction.Time, err = ParseTime(record[columns["time"]]); err != nil {
+			return transactions, errors.Wrapf(err, "line %d", line)
+		}
+		switch transaction.Kind {
+		case "buy", "sell", "receive", "send":
+		default:
+			return transactions, errors.Errorf("line %d: type %q should be buy, sell, receive or send", line, transaction.Kind)
+		}
+		amount, amountErr := strconv.ParseFloat(strings.TrimSpace(record[columns["amount"]]), 64)
+		if amountErr != nil || amount <= 0 {
+			return transactions, errors.Errorf("line %d: amount %q should be a number more than 0", line, record[columns["amount"]])
+		}
+		transaction.Amount = amount
+
+		if hasValue && strings.TrimSpace(record[valueColumn]) != "" {
+			value, valueErr := strconv.ParseFloat(strings.TrimSpace(record[valueColumn]), 64)
+			if valueErr != nil || value < 0 {
+				return transactions, errors.Errorf("line %d: value %q should be a number", line, record[valueColumn])
+			}
+			transaction.Value = &value
+		}
+		transactions = append(transactions, transaction)
+	}
+}
+
+// ValueTransactions fills in the fiat value of transactions that don't have one from the price history
+func (history *PriceHistory) ValueTransactions(transactions []Transaction, fiat string, policy string, tolerance time.Duration) error {
+	for i := range transactions {
+		if transactions[i].Value != nil {
+			continue
+		}
+		price, priceErr := history.PriceAt(transactions[i].Time, fiat, policy, tolerance)
+		if priceErr != nil {
+			return errors.Wrapf(priceErr, "line %d", transactions[i].Line)
+		}
+		value := ConvertTurtleFiatPrice(FiatPrice{Trtl: 1, Fiat: fiat, Value: price.Price}, transactions[i].Amount).Value
+		transactions[i].Value = &value
+	}
+	return nil
+}
+
+// NewTaxReport works out the realized gain or loss of each disposal with the cost basis method
+// The transactions need their values filled in, they are taken in time order
+func NewTaxReport(transactions []Transaction, fiat string, method string) (report TaxReport, err error) {
+	if method != MethodFIFO && method != MethodLIFO && method != MethodAverage {
+		return report, errors.Errorf("method must be %s, %s or %s, not %s", MethodFIFO, MethodLIFO, MethodAverage, method)
+	}
+	report = TaxReport{Fiat: fiat, Method: method, Disposals: []Disposal{}, Years: []TaxYear{}}
+
+	sorted := make([]Transaction, len(transactions))
+	copy(sorted, transactions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	var lots []lot
+	years := map[int]*TaxYear{}
+	for _, transaction := range sorted {
+		if transaction.Value == nil {
+			return report, errors.Errorf("line %d has no value", transaction.Line)
+		}
+		if transaction.Kind == "buy" || transaction.Kind == "receive" {
+			lots = append(lots, lot{amount: transaction.Amount, cost: *transaction.Value})
+			continue
+		}
+
+		var costBasis float64
+		lots, costBasis, err = takeLots(lots, transaction.Amount, method)
+		if err != nil {
+			return report, errors.Wrapf(err, "line %d", transaction.Line)
+		}
+		disposal := Disposal{
+			Transaction: transaction,
+			CostBasis:   costBasis,
+			Gain:        *transaction.Value - costBasis,
+		}
+		report.Disposals = append(report.Disposals, disposal)
+
+		year := transaction.Time.UTC().Year()
+		if years[year] == nil {
+			years[year] = &TaxYear{Year: year}
+		}
+		years[year].Proceeds += *disposal.Value
+		years[year].CostBasis += disposal.CostBasis
+		years[year].Gain += disposal.Gain
+	}
+
+	for _, year := range years {
+		report.Years = append(report.Years, *year)
+	}
+	sort.Slice(report.Years, func(i, j int) bool { return report.Years[i].Year < report.Years[j].Year })
+	for _, held := range lots {
+		report.Held += held.amount
+		report.HeldCost += held.cost
+	}
+	return report, nil
+}
+
+// takeLots takes amount out of the lots and returns what's left and what the amount taken cost
+func takeLots(lots []lot, amount float64, method string) (remaining []lot, costBasis float64, err error) {
+	held := 0.0
+	for _, holding := range lots {
+		held += holding.amount
+	}
+	if amount > held+dust {
+		return lots, costBasis, errors.Errorf("disposes of %s TRTL but only %s is held",
+			strconv.FormatFloat(amount, 'f', -1, 64), strconv.FormatFloat(held, 'f', -1, 64))
+	}
+
+	if method == MethodAverage {
+		cost := 0.0
+		for _, holding := range lots {
+			cost += holding.cost
+		}
+		costBasis = cost * amount / held
+		if held-amount <= dust {
+			return nil, costBasis, nil
+		}
+		return []lot{{amount: held - amount, cost: cost - costBasis}}, costBasis, nil
+	}
+
+	remaining = append([]lot{}, lots...)
+	for amount > dust && len(remaining) > 0 {
+		i := 0
+		if method == MethodLIFO {
+			i = len(remaining) - 1
+		}
+		taken := remaining[i].amount
+		if taken > amount {
+			taken = amount
+		}
+		share := remaining[i].cost * taken / remaining[i].amount
+		costBasis += share
+		amount -= taken
+		remaining[i].amount -= taken
+		remaining[i].cost -= share
+		if remaining[i].amount <= dust {
+			remaining = append(remaining[:i], remaining[i+1:]...)
+		}
+	}
+	return remaining, costBasis, nil
+}
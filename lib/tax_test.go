@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ledger = `time,type,amount,value
+2018-01-01,buy,1000,10
+2018-02-01,buy,1000,30
+2018-06-01,sell,1500,60
+2019-03-01,receive,500,
+2019-04-01,send,600,36
+`
+
+func TestTaxReportMethods(t *testing.T) {
+	transactions, err := ParseLedgerCSV(strings.NewReader(ledger))
+	assert.Nil(t, err)
+	assert.Len(t, transactions, 5)
+	received := 20.0
+	transactions[3].Value = &received
+
+	fifo, err := NewTaxReport(transactions, "USD", MethodFIFO)
+	assert.Nil(t, err)
+	assert.Len(t, fifo.Years, 2)
+	// 1000 at 0.01 then 500 at 0.03
+	assert.InDelta(t, 25, fifo.Years[0].CostBasis, 1e-9)
+	assert.InDelta(t, 35, fifo.Years[0].Gain, 1e-9)
+	// the last 500 of the second buy then 100 of the receive
+	assert.InDelta(t, 19, fifo.Years[1].CostBasis, 1e-9)
+	assert.InDelta(t, 400, fifo.Held, 1e-9)
+	assert.InDelta(t, 16, fifo.HeldCost, 1e-9)
+
+	lifo, err := NewTaxReport(transactions, "USD", MethodLIFO)
+	assert.Nil(t, err)
+	assert.InDelta(t, 35, lifo.Years[0].CostBasis, 1e-9)
+	assert.InDelta(t, 21, lifo.Years[1].CostBasis, 1e-9)
+
+	average, err := NewTaxReport(transactions, "USD", MethodAverage)
+	assert.Nil(t, err)
+	assert.InDelta(t, 30, average.Years[0].CostBasis, 1e-9)
+	assert.InDelta(t, 30, average.Years[0].Gain, 1e-9)
+	assert.InDelta(t, 18, average.Years[1].CostBasis, 1e-9)
+
+	_, err = NewTaxReport(transactions[2:3], "USD", MethodFIFO)
+	assert.Contains(t, err.Error(), "line 4")
+	transactions[3].Value = nil
+	_, err = NewTaxReport(transactions, "USD", MethodFIFO)
+	assert.Contains(t, err.Error(), "line 5 has no value")
+}
+
+func TestValueTransactions(t *testing.T) {
+	history, _ := OpenPriceHistory("")
+	history.Record(PricePoint{Time: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC), TrtlToBtc: 1e-6, BtcToFiat: map[string]float64{"USD": 40000}})
+
+	transactions, _ := ParseLedgerCSV(strings.NewReader(ledger))
+	assert.Nil(t, history.ValueTransactions(transactions, "USD", PolicyNearest, 24*time.Hour))
+	assert.InDelta(t, 20, *transactions[3].Value, 1e-9)
+	assert.Equal(t, 10.0, *transactions[0].Value)
+
+	// a gift received is worth nothing, that's not the same as not saying what it was worth
+	gifted, _ := ParseLedgerCSV(strings.NewReader("time,type,amount,value\n2019-03-01,receive,500,0\n"))
+	assert.Nil(t, history.ValueTransactions(gifted, "USD", PolicyNearest, 24*time.Hour))
+	assert.Equal(t, 0.0, *gifted[0].Value)
+
+	_, err := ParseLedgerCSV(strings.NewReader("time,type,amount\n2018-01-01,gift,5\n"))
+	assert.Contains(t, err.Error(), "line 2")
+}
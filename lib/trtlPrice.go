@@ -1,48 +1,169 @@
 package lib
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
-// PullTrtlToBtcPrice hits the tradeogre API to get the current Turtle to Bitcoin price
-func PullTrtlToBtcPrice() (btcPrice float64, err error) {
-	trtlToBtcURL := "https://tradeogre.com/api/v1/ticker/BTC-TRTL"
-	// {"initialprice":"0.00000010","price":"0.00000016","high":"0.00000016","low":"0.00000006","volume":"17.18630467"}
-	type tradeOgreResult struct {
-		// Number int `json:"number"`
-		Price string `json:"price"`
+// ErrQuotesQuarantined is returned when every quote in a pull looked wrong and was held back
+var ErrQuotesQuarantined = errors.New("every BTC-TRTL quote looked wrong and was quarantined")
+
+// tradeOgreTicker is everything tradeogre returns for a market's ticker
+// {"initialprice":"0.00000010","price":"0.00000016","high":"0.00000016","low":"0.00000006","volume":"17.18630467","bid":"0.00000015","ask":"0.00000016"}
+type tradeOgreTicker struct {
+	Error        string `json:"error"`
+	InitialPrice string `json:"initialprice"`
+	Price        string `json:"price"`
+	High         string `json:"high"`
+	Low          string `json:"low"`
+	Volume       string `json:"volume"`
+	Bid          string `json:"bid"`
+	Ask          string `json:"ask"`
+}
+
+// PullTrtlToBtcPrice gets the current Turtle to Bitcoin price from every configured BTC-TRTL source
+// The prices are averaged by weight, a source that fails or whose quote is quarantined is skipped unless they all are
+// sources names the ones that were averaged, like tradeogre:BTC-TRTL
+func PullTrtlToBtcPrice() (btcPrice float64, sources []string, err error) {
+	return PullTrtlToBtcPriceContext(context.Background())
+}
+
+// PullTrtlToBtcPriceContext is PullTrtlToBtcPrice that gives up when ctx is done
+func PullTrtlToBtcPriceContext(ctx context.Context) (btcPrice float64, sources []string, err error) {
+	quote, err := pullTrtlToBtcQuote(ctx)
+	return quote.price, quote.sources, err
+}
+
+// pullTrtlToBtcQuote is PullTrtlToBtcPriceContext that also says when the price was pulled and adds up the BTC
+// traded on the averaged sources
+func pullTrtlToBtcQuote(ctx context.Context) (quote btcQuote, err error) {
+	sources := btcSources()
+	if len(sources) == 0 {
+		return quote, errors.New("no BTC-TRTL sources are configured")
 	}
 
+	quote.fetchedAt = time.Now()
+	var quotes []sourceQuote
+	var sourceErrs []string
+	for _, source := range sources {
+		ticker, pullErr := pullTradeOgreTicker(ctx, source)
+		price, parseErr := strconv.ParseFloat(ticker.Price, 64)
+		if pullErr == nil && parseErr != nil {
+			pullErr = parseErr
+		}
+		if pullErr != nil {
+			sourceErrs = append(sourceErrs, source.Name+": "+pullErr.Error())
+			continue
+		}
+		// tradeogre's volume is the BTC traded over the last 24 hours, a source without one still counts for the price
+		volume, _ := strconv.ParseFloat(ticker.Volume, 64)
+		quotes = append(quotes, sourceQuote{source: source, price: price, volume: volume})
+	}
+	if len(quotes) == 0 {
+		return quote, errors.New(strings.Join(sourceErrs, "; "))
+	}
+
+	var weightedSum, totalWeight float64
+	var accepted []Source
+	for _, screened := range screenQuotes(ctx, quotes, recentBtcSamples(CurrentSettings().Anomaly)) {
+		weightedSum += screened.price * screened.source.Weight
+		totalWeight += screened.source.Weight
+		quote.volume += screened.volume
+		accepted = append(accepted, screened.source)
+	}
+	if totalWeight == 0 {
+		return btcQuote{}, ErrQuotesQuarantined
+	}
+	quote.price = weightedSum / totalWeight
+	quote.sources = sourceNames(accepted)
+	return quote, nil
+}
+
+// PullSourcePrice hits a single source's API to get the last trade price of its market
+func PullSourcePrice(source Source) (price float64, err error) {
+	return PullSourcePriceContext(context.Background(), source)
+}
+
+// PullSourcePriceContext is PullSourcePrice that gives up when ctx is done
+func PullSourcePriceContext(ctx context.Context, source Source) (price float64, err error) {
+	if source.Kind == "coinbase" {
+		return pullCoinbaseSpot(ctx, source.URL, source.Name, source.Pair)
+	}
+	ticker, tickerErr := pullTradeOgreTicker(ctx, source)
+	if tickerErr != nil {
+		return price, tickerErr
+	}
+
+	price, err = strconv.ParseFloat(ticker.Price, 64)
+	return price, err
+}
+
+// getSourcePrice is a source's last trade price, from the cache when it was pulled within the TRTL cache TTL
+func getSourcePrice(ctx context.Context, source Source, forceCheck bool) (price float64, err error) {
+	cacheKey := source.Name + " " + source.Pair
+	if cached, _, ok := prices.get(cacheKey, CurrentSettings().TrtlCacheTTL); ok && !forceCheck {
+		return cached, nil
+	}
+
+	fetchedAt := time.Now()
+	price, err = PullSourcePriceContext(ctx, source)
+	if err == nil {
+		prices.set(cacheKey, price, fetchedAt)
+	}
+	return price, err
+}
+
+// getTradeOgreTicker is a source's ticker, from the cache when it was pulled within the TRTL cache TTL
+func getTradeOgreTicker(ctx context.Context, source Source, forceCheck bool) (ticker tradeOgreTicker, fetchedAt time.Time, err error) {
+	cacheKey := source.Name + " " + source.Pair
+	if cached, cachedAt, ok := prices.getTicker(cacheKey, CurrentSettings().TrtlCacheTTL); ok && !forceCheck {
+		return cached, cachedAt, nil
+	}
+
+	fetchedAt = time.Now()
+	ticker, err = pullTradeOgreTicker(ctx, source)
+	if err == nil {
+		prices.setTicker(cacheKey, ticker, fetchedAt)
+	}
+	return ticker, fetchedAt, err
+}
+
+// pullTradeOgreTicker hits the tradeogre API to get the ticker for a market like BTC-TRTL
+func pullTradeOgreTicker(ctx context.Context, source Source) (ticker tradeOgreTicker, err error) {
+	tickerURL := fmt.Sprintf("%s/ticker/%s", source.URL, source.Pair)
+
 	client := http.Client{
-		Timeout: time.Second * 3, // Maximum of 3 secs
+		Timeout: CurrentSettings().ExchangeTimeout,
 	}
 
-	trtlToBtcReq, reqErr := http.NewRequest(http.MethodGet, trtlToBtcURL, nil)
+	tickerReq, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, tickerURL, nil)
 
 	if reqErr != nil {
-		return btcPrice, reqErr
+		return ticker, reqErr
 	}
 
-	res, getErr := client.Do(trtlToBtcReq)
-	if getErr != nil {
-		return btcPrice, getErr
+	body, fetchErr := fetchUpstream(ctx, &client, source.Name, tickerReq)
+	if fetchErr != nil {
+		return ticker, fetchErr
 	}
 
-	body, readErr := ioutil.ReadAll(res.Body)
-	if readErr != nil {
-		return btcPrice, readErr
-	}
+	return parseTradeOgreTicker(body)
+}
 
-	tradeOgreRes := tradeOgreResult{}
-	jsonErr := json.Unmarshal(body, &tradeOgreRes)
+func parseTradeOgreTicker(body []byte) (ticker tradeOgreTicker, err error) {
+	jsonErr := json.Unmarshal(body, &ticker)
 	if jsonErr != nil {
-		return btcPrice, jsonErr
+		return ticker, jsonErr
 	}
-
-	btcPrice, err = strconv.ParseFloat(tradeOgreRes.Price, 64)
-	return btcPrice, err
+	if ticker.Error != "" {
+		return ticker, errors.New(ticker.Error)
+	}
+	return ticker, nil
 }
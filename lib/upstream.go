@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// fetchUpstream does an upstream API call and reads the body, logging the source, URL, status, latency and
+// what kind of error it was so a failed request can be traced back to the upstream that broke it
+// The URL is logged and put in errors without its credentials or query, configured URLs can carry API keys
+func fetchUpstream(ctx context.Context, client *http.Client, source string, req *http.Request) (body []byte, err error) {
+	started := time.Now()
+	status := 0
+	safeURL := redactURL(req.URL)
+	defer func() {
+		attrs := []interface{}{
+			"source", source,
+			"url", safeURL,
+			"status", status,
+			"latency_ms", float64(time.Since(started).Microseconds()) / 1000,
+		}
+		if err != nil {
+			Logger(ctx).Warn("upstream call failed", append(attrs, "error_class", errorClass(err), "error", err.Error())...)
+			return
+		}
+		Logger(ctx).Info("upstream call", attrs...)
+	}()
+
+	res, getErr := client.Do(req)
+	if urlErr, ok := getErr.(*url.Error); ok {
+		getErr = &url.Error{Op: urlErr.Op, URL: safeURL, Err: urlErr.Err}
+	}
+	if getErr != nil {
+		return body, getErr
+	}
+	defer res.Body.Close()
+	status = res.StatusCode
+
+	body, readErr := ioutil.ReadAll(res.Body)
+	if readErr != nil {
+		return body, readErr
+	}
+	if res.StatusCode >= 400 {
+		return body, &statusError{source: source, status: res.Status}
+	}
+	return body, nil
+}
+
+// redactURL is just the scheme, host and path of an upstream URL
+func redactURL(upstreamURL *url.URL) string {
+	return upstreamURL.Scheme + "://" + upstreamURL.Host + upstreamURL.Path
+}
+
+// statusError is an upstream answering with a 4xx or 5xx
+type statusError struct {
+	source string
+	status string
+}
+
+func (err *statusError) Error() string {
+	return err.source + " returned " + err.status
+}
+
+// errorClass buckets upstream errors into timeout, canceled, status, network or other
+func errorClass(err error) string {
+	var netErr net.Error
+	var status *statusError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &status):
+		return "status"
+	case errors.As(err, &netErr):
+		return "network"
+	}
+	return "other"
+}
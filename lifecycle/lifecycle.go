@@ -0,0 +1,135 @@
+package lifecycle
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+// Component is background work that runs alongside the web server, like a poller or alert dispatcher
+type Component interface {
+	Name() string
+	Start() error
+	Stop(ctx context.Context) error
+}
+
+// Manager starts components in the order they were added and stops them in reverse
+type Manager struct {
+	lock       sync.Mutex
+	components []Component
+	started    []Component
+}
+
+// Add registers a component to be started with the others
+func (manager *Manager) Add(component Component) {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+	manager.components = append(manager.components, component)
+}
+
+// Start starts every component in order
+// If one fails the ones already started are stopped again so nothing is left half running
+func (manager *Manager) Start() error {
+	manager.lock.Lock()
+	components := manager.components
+	manager.lock.Unlock()
+
+	for _, component := range components {
+		if err := component.Start(); err != nil {
+			stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			manager.Stop(stopCtx)
+			return errors.Wrapf(err, "Problem starting %s", component.Name())
+		}
+		lib.Logger(context.Background()).Info("started component", "component", component.Name())
+
+		manager.lock.Lock()
+		manager.started = append(manager.started, component)
+		manager.lock.Unlock()
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse order, giving up on any still running when ctx is done
+func (manager *Manager) Stop(ctx context.Context) error {
+	manager.lock.Lock()
+	started := manager.started
+	manager.started = nil
+	manager.lock.Unlock()
+
+	var stopErrs []string
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := started[i].Stop(ctx); err != nil {
+			stopErrs = append(stopErrs, started[i].Name()+": "+err.Error())
+			continue
+		}
+		lib.Logger(ctx).Info("stopped component", "component", started[i].Name())
+	}
+
+	if len(stopErrs) > 0 {
+		return errors.Errorf("Problem stopping %s", strings.Join(stopErrs, "; "))
+	}
+	return nil
+}
+
+// Ticker is a component that calls a function every interval until it's stopped
+type Ticker struct {
+	name     string
+	interval time.Duration
+	tick     func(ctx context.Context)
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewTicker makes a component that runs tick every interval, the context is cancelled when it's stopped
+func NewTicker(name string, interval time.Duration, tick func(ctx context.Context)) *Ticker {
+	return &Ticker{name: name, interval: interval, tick: tick}
+}
+
+// Name is what the ticker is called in logs
+func (ticker *Ticker) Name() string {
+	return ticker.name
+}
+
+// Start begins ticking in the background
+func (ticker *Ticker) Start() error {
+	if ticker.interval <= 0 {
+		return errors.Errorf("%s needs an interval more than 0", ticker.name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker.cancel = cancel
+	ticker.done = make(chan struct{})
+
+	go func() {
+		defer close(ticker.done)
+		timer := time.NewTicker(ticker.interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				ticker.tick(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop cancels the current tick and waits for it to finish
+func (ticker *Ticker) Stop(ctx context.Context) error {
+	if ticker.cancel == nil {
+		return nil
+	}
+	ticker.cancel()
+	select {
+	case <-ticker.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
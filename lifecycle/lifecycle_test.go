@@ -0,0 +1,63 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingComponent struct {
+	name     string
+	startErr error
+	events   *[]string
+}
+
+func (component recordingComponent) Name() string { return component.name }
+
+func (component recordingComponent) Start() error {
+	*component.events = append(*component.events, "start "+component.name)
+	return component.startErr
+}
+
+func (component recordingComponent) Stop(ctx context.Context) error {
+	*component.events = append(*component.events, "stop "+component.name)
+	return nil
+}
+
+func TestManagerStartsInOrderAndStopsInReverse(t *testing.T) {
+	var events []string
+	manager := &Manager{}
+	manager.Add(recordingComponent{name: "poller", events: &events})
+	manager.Add(recordingComponent{name: "alerts", events: &events})
+
+	assert.Nil(t, manager.Start())
+	assert.Nil(t, manager.Stop(context.Background()))
+	assert.Equal(t, []string{"start poller", "start alerts", "stop alerts", "stop poller"}, events)
+}
+
+func TestManagerUnwindsWhenAStartFails(t *testing.T) {
+	var events []string
+	manager := &Manager{}
+	manager.Add(recordingComponent{name: "poller", events: &events})
+	manager.Add(recordingComponent{name: "alerts", startErr: errors.New("boom"), events: &events})
+	manager.Add(recordingComponent{name: "never", events: &events})
+
+	assert.NotNil(t, manager.Start())
+	assert.Equal(t, []string{"start poller", "start alerts", "stop poller"}, events)
+}
+
+func TestTickerStopsCleanly(t *testing.T) {
+	ticks := make(chan struct{}, 1)
+	ticker := NewTicker("test", time.Millisecond, func(ctx context.Context) {
+		select {
+		case ticks <- struct{}{}:
+		default:
+		}
+	})
+	assert.Nil(t, ticker.Start())
+	<-ticks
+	assert.Nil(t, ticker.Stop(context.Background()))
+}
@@ -1,32 +1,88 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"log"
 	"os"
 
-	"github.com/gin-gonic/gin"
 	_ "github.com/joho/godotenv/autoload"
-	"github.com/thinkerou/favicon"
-	handlers "github.com/y4htse/turtle-utils/handlers"
+	config "github.com/y4htse/turtle-utils/config"
 )
 
+const usage = `Usage: turtle-utils [--config file.yaml] <command> [options]
+
+Commands:
+  serve                  run the web server (the default with no command)
+  price                  print the current TRTL price
+  convert <trtl>         convert an amount of TRTL
+  history                print recorded prices from $HISTORY_FILE
+  history import <csv>   backfill $HISTORY_FILE from a CSV
+  tax <ledger.csv>       realized gains per year from a ledger of TRTL transactions
+
+The config file can also be given with $CONFIG_FILE.
+Run turtle-utils <command> -h for a command's options.
+`
+
+// configPath is the YAML config file, kept so SIGHUP can reload it
+var configPath string
+
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		log.Fatalln("Must set $PORT")
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("turtle-utils", flag.ContinueOnError)
+	flags.StringVar(&configPath, "config", os.Getenv("CONFIG_FILE"), "YAML config file")
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	if err := flags.Parse(args); err != nil {
+		return parseErrorCode(err)
+	}
+	args = flags.Args()
+
+	if len(args) == 0 {
+		return serveCommand(args)
+	}
+
+	switch args[0] {
+	case "serve":
+		return serveCommand(args[1:])
+	case "price":
+		return withConfig(priceCommand, args[1:])
+	case "convert":
+		return withConfig(convertCommand, args[1:])
+	case "history":
+		return withConfig(historyCommand, args[1:])
+	case "tax":
+		return withConfig(taxCommand, args[1:])
+	case "help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "Unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// loadConfig loads and validates the config file, letting overrides (from flags) go on top before it's applied
+func loadConfig(overrides func(*config.Config)) (config.Config, error) {
+	loaded, err := config.Load(configPath)
+	if err != nil {
+		return loaded, err
+	}
+	if overrides != nil {
+		overrides(&loaded)
+	}
+	if err := loaded.Validate(); err != nil {
+		return loaded, err
+	}
+	config.Apply(loaded)
+	return loaded, nil
+}
+
+func withConfig(command func([]string) int, args []string) int {
+	if _, err := loadConfig(nil); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
 	}
-	r := gin.Default()
-	r.Use(favicon.New("favicon.ico"))
-	r.LoadHTMLGlob("templates/*")
-	r.GET("/", func(c *gin.Context) {
-		handlers.BaseHandler(c)
-	})
-	r.GET("/price", func(c *gin.Context) {
-		handlers.PriceHandler(c)
-	})
-	r.GET("/convert", func(c *gin.Context) {
-		handlers.ConvertHandler(c)
-	})
-	r.Run(fmt.Sprintf("0.0.0.0:%s", port))
+	return command(args)
 }
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	alerts "github.com/y4htse/turtle-utils/alerts"
+	config "github.com/y4htse/turtle-utils/config"
+	handlers "github.com/y4htse/turtle-utils/handlers"
+	lib "github.com/y4htse/turtle-utils/lib"
+	lifecycle "github.com/y4htse/turtle-utils/lifecycle"
+	web "github.com/y4htse/turtle-utils/web"
+)
+
+func serveCommand(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	port := flags.String("port", "", "port to listen on, overrides $PORT and the config file")
+	address := flags.String("address", "", "address to listen on, overrides $ADDRESS and the config file")
+	socket := flags.String("socket", "", "unix socket to listen on instead of address:port")
+	assets := flags.String("assets", "", "directory to read templates and static files from instead of the built in ones, like ./web")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorCode(err)
+	}
+
+	overrides := func(loaded *config.Config) {
+		if *port != "" {
+			loaded.Server.Port = *port
+		}
+		if *address != "" {
+			loaded.Server.Address = *address
+		}
+		if *socket != "" {
+			loaded.Server.Socket = *socket
+		}
+		if *assets != "" {
+			loaded.Server.Assets = *assets
+		}
+	}
+	serverConfig, configErr := loadConfig(overrides)
+	if configErr != nil {
+		lib.Logger(context.Background()).Error("Problem loading the config", "error", configErr.Error())
+		return exitUsage
+	}
+	engine, routerErr := router(serverConfig.Server)
+	if routerErr != nil {
+		lib.Logger(context.Background()).Error("Problem setting up the routes", "error", routerErr.Error())
+		return exitError
+	}
+	go reloadOnHangup(overrides)
+
+	manager := &lifecycle.Manager{}
+	if serverConfig.Poller.Interval > 0 {
+		manager.Add(lifecycle.NewTicker("price poller", serverConfig.Poller.Interval, pollPrices))
+	}
+	if serverConfig.Arbitrage.Interval > 0 {
+		dispatcher := alerts.NewDispatcher(serverConfig.Alerts.Webhooks, serverConfig.Alerts.Cooldown, serverConfig.Alerts.Timeout)
+		manager.Add(dispatcher)
+		manager.Add(lifecycle.NewTicker("arbitrage checker", serverConfig.Arbitrage.Interval, checkArbitrage(dispatcher)))
+	}
+	if err := manager.Start(); err != nil {
+		lib.Logger(context.Background()).Error("Problem starting components", "error", err.Error())
+		return exitError
+	}
+
+	srv := &http.Server{
+		Handler:      engine,
+		ReadTimeout:  serverConfig.Server.ReadTimeout,
+		WriteTimeout: serverConfig.Server.WriteTimeout,
+		IdleTimeout:  serverConfig.Server.IdleTimeout,
+	}
+	listener, listenErr := listen(serverConfig.Server)
+	if listenErr != nil {
+		lib.Logger(context.Background()).Error("Problem listening", "error", listenErr.Error())
+		manager.Stop(context.Background())
+		return exitError
+	}
+
+	serveErrs := make(chan error, 1)
+	go func() {
+		lib.Logger(context.Background()).Info("Listening", "address", listener.Addr().String())
+		if serverConfig.Server.TLSCert != "" {
+			serveErrs <- srv.ServeTLS(listener, serverConfig.Server.TLSCert, serverConfig.Server.TLSKey)
+		} else {
+			serveErrs <- srv.Serve(listener)
+		}
+	}()
+
+	stops := make(chan os.Signal, 1)
+	signal.Notify(stops, syscall.SIGTERM, os.Interrupt)
+
+	exitCode := exitOK
+	select {
+	case err := <-serveErrs:
+		lib.Logger(context.Background()).Error("Server stopped", "error", err.Error())
+		exitCode = exitError
+	case sig := <-stops:
+		lib.Logger(context.Background()).Info("Draining requests", "signal", sig.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		lib.Logger(ctx).Error("Problem shutting down the server", "error", err.Error())
+		exitCode = exitError
+	}
+	if err := manager.Stop(ctx); err != nil {
+		lib.Logger(ctx).Error("Problem stopping components", "error", err.Error())
+		exitCode = exitError
+	}
+	return exitCode
+}
+
+// listen opens the unix socket or TCP address the server is configured for
+func listen(server config.Server) (net.Listener, error) {
+	if server.Socket != "" {
+		// a socket left behind by a server that didn't shut down cleanly would make Listen fail
+		if info, err := os.Stat(server.Socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(server.Socket)
+		}
+		return net.Listen("unix", server.Socket)
+	}
+	return net.Listen("tcp", net.JoinHostPort(server.Address, server.Port))
+}
+
+func router(server config.Server) (*gin.Engine, error) {
+	assets := web.Assets(server.Assets)
+	htmlRender, templatesErr := web.NewHTMLRender(assets, server.Assets != "")
+	if templatesErr != nil {
+		return nil, templatesErr
+	}
+	static, staticErr := fs.Sub(assets, "static")
+	if staticErr != nil {
+		return nil, staticErr
+	}
+
+	r := gin.New()
+	r.HTMLRender = htmlRender
+	r.Use(gin.Recovery())
+	r.Use(handlers.RequestID())
+	r.Use(handlers.AccessLog())
+	r.GET("/favicon.ico", func(c *gin.Context) {
+		icon, iconErr := fs.ReadFile(assets, "favicon.ico")
+		if iconErr != nil {
+			c.AbortWithStatus(404)
+			return
+		}
+		c.Data(200, "image/x-icon", icon)
+	})
+	r.StaticFS("/static", http.FS(static))
+	r.Use(handlers.RateLimit())
+	r.Use(handlers.Deadline())
+	r.GET("/", func(c *gin.Context) {
+		handlers.BaseHandler(c)
+	})
+	r.GET("/price", func(c *gin.Context) {
+		handlers.PriceHandler(c)
+	})
+	r.GET("/convert", func(c *gin.Context) {
+		handlers.ConvertHandler(c)
+	})
+	r.GET("/market", handlers.Feature("market"), func(c *gin.Context) {
+		handlers.MarketHandler(c)
+	})
+	r.GET("/markets", handlers.Feature("markets"), func(c *gin.Context) {
+		handlers.MarketsHandler(c)
+	})
+	r.GET("/supply", handlers.Feature("supply"), func(c *gin.Context) {
+		handlers.SupplyHandler(c)
+	})
+	r.GET("/marketcap", handlers.Feature("supply"), func(c *gin.Context) {
+		handlers.MarketCapHandler(c)
+	})
+	r.GET("/emission", handlers.Feature("emission"), func(c *gin.Context) {
+		handlers.EmissionHandler(c)
+	})
+	r.GET("/emission/projection", handlers.Feature("emission"), func(c *gin.Context) {
+		handlers.EmissionProjectionHandler(c)
+	})
+	r.GET("/mining/profit", handlers.Feature("mining"), func(c *gin.Context) {
+		handlers.MiningProfitHandler(c)
+	})
+	r.GET("/chart", handlers.Feature("charts"), func(c *gin.Context) {
+		handlers.ChartHandler(c)
+	})
+	r.GET("/chart.svg", handlers.Feature("charts"), func(c *gin.Context) {
+		handlers.ChartSVGHandler(c)
+	})
+	r.GET("/indicators", handlers.Feature("indicators"), func(c *gin.Context) {
+		handlers.IndicatorsHandler(c)
+	})
+	r.GET("/badge.svg", handlers.Feature("badges"), func(c *gin.Context) {
+		handlers.BadgeHandler(c)
+	})
+	r.POST("/api/v1/convert/batch", handlers.Feature("batch"), func(c *gin.Context) {
+		handlers.BatchConvertHandler(c)
+	})
+	r.POST("/api/v1/tax/report", handlers.Feature("tax"), func(c *gin.Context) {
+		handlers.TaxReportHandler(c)
+	})
+	r.GET("/quote", handlers.Feature("quotes"), func(c *gin.Context) {
+		handlers.QuoteHandler(c)
+	})
+	r.GET("/oracle/price", handlers.Feature("oracle"), func(c *gin.Context) {
+		handlers.OraclePriceHandler(c)
+	})
+	r.GET("/.well-known/oracle-key", handlers.Feature("oracle"), func(c *gin.Context) {
+		handlers.OracleKeyHandler(c)
+	})
+	r.GET("/admin/quarantine", handlers.Admin(), func(c *gin.Context) {
+		handlers.QuarantineHandler(c)
+	})
+	r.DELETE("/admin/quarantine", handlers.Admin(), func(c *gin.Context) {
+		handlers.ClearQuarantineHandler(c)
+	})
+	return r, nil
+}
+
+// pollPrices pulls the price in every configured fiat so the history keeps filling up between requests
+func pollPrices(ctx context.Context) {
+	for _, fiat := range lib.Fiats() {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := lib.GetFiatPriceHashContext(ctx, fiat, true); err != nil {
+			lib.Logger(ctx).Warn("Problem polling the price", "fiat", fiat, "error", err.Error())
+		}
+	}
+}
+
+// checkArbitrage looks for spreads between exchanges above the configured threshold and alerts each one
+// It only needs the exchanges, not a BTC price, so a fiat outage doesn't stop it
+func checkArbitrage(dispatcher *alerts.Dispatcher) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		comparison, err := lib.CompareMarketsContext(ctx, "", config.Current().Arbitrage.Threshold, true)
+		if err != nil {
+			lib.Logger(ctx).Warn("Problem checking for arbitrage", "error", err.Error())
+			return
+		}
+		for _, opportunity := range comparison.Opportunities {
+			dispatcher.Send(alerts.Alert{
+				Kind: "arbitrage",
+				Key:  "arbitrage " + opportunity.Pair + " " + opportunity.BuySource + " " + opportunity.SellSource,
+				Message: fmt.Sprintf("Buy %s on %s at %.8f and sell on %s at %.8f for %.2f%% after fees",
+					opportunity.Pair, opportunity.BuySource, opportunity.BuyPrice, opportunity.SellSource, opportunity.SellPrice, opportunity.NetSpreadPercent),
+				Data: opportunity,
+			})
+		}
+	}
+}
+
+// reloadOnHangup reloads the config file on SIGHUP, keeping the old config if the new one is invalid
+// The server, poller, arbitrage interval and alert settings can't change without a restart
+func reloadOnHangup(overrides func(*config.Config)) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		previous := config.Current()
+		reloaded, err := loadConfig(overrides)
+		if err != nil {
+			lib.Logger(context.Background()).Error("Not reloading config", "error", err.Error())
+			continue
+		}
+		if reloaded.Server != previous.Server || reloaded.Poller != previous.Poller ||
+			reloaded.Arbitrage.Interval != previous.Arbitrage.Interval || !reflect.DeepEqual(reloaded.Alerts, previous.Alerts) {
+			lib.Logger(context.Background()).Warn("Server, poller, arbitrage interval and alert changes need a restart to take effect")
+		}
+		lib.Logger(context.Background()).Info("Reloaded config")
+	}
+}
@@ -0,0 +1,70 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Badge colors, the same as shields.io's
+const (
+	Green     = "#4c1"
+	Red       = "#e05d44"
+	Blue      = "#007ec6"
+	LightGrey = "#9f9f9f"
+)
+
+// BadgeStyles are the shields.io styles Badge can draw
+var BadgeStyles = []string{"flat", "flat-square"}
+
+// Badge draws a shields.io style badge with label on the left in grey and message on the right in color
+func Badge(label string, message string, color string, style string) []byte {
+	labelWidth, messageWidth := textWidth(label)+10, textWidth(message)+10
+	width := labelWidth + messageWidth
+	radius, gradient := 3, true
+	if style == "flat-square" {
+		radius, gradient = 0, false
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`,
+		width, escape(label), escape(message))
+	fmt.Fprintf(&body, `<title>%s: %s</title>`, escape(label), escape(message))
+	if gradient {
+		body.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	}
+	fmt.Fprintf(&body, `<clipPath id="r"><rect width="%d" height="20" rx="%d" fill="#fff"/></clipPath>`, width, radius)
+	fmt.Fprintf(&body, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/>`,
+		labelWidth, labelWidth, messageWidth, color)
+	if gradient {
+		fmt.Fprintf(&body, `<rect width="%d" height="20" fill="url(#s)"/>`, width)
+	}
+	body.WriteString(`</g><g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	for _, text := range []struct {
+		x     float64
+		value string
+	}{{float64(labelWidth) / 2, label}, {float64(labelWidth) + float64(messageWidth)/2, message}} {
+		if gradient {
+			fmt.Fprintf(&body, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text>`, text.x, escape(text.value))
+		}
+		fmt.Fprintf(&body, `<text x="%.1f" y="14">%s</text>`, text.x, escape(text.value))
+	}
+	body.WriteString("</g></svg>\n")
+	return body.Bytes()
+}
+
+// textWidth roughly measures text in 11px Verdana, close enough that badges don't clip or look padded
+func textWidth(text string) int {
+	width := 0.0
+	for _, char := range text {
+		switch {
+		case strings.ContainsRune(".,:;|!il1' ", char):
+			width += 3.8
+		case char >= 'A' && char <= 'Z', char == 'm', char == 'w', char == '%':
+			width += 8.5
+		default:
+			width += 7
+		}
+	}
+	return int(width + 0.5)
+}
@@ -0,0 +1,20 @@
+package svg
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBadge(t *testing.T) {
+	flat := string(Badge("TRTL", "$0.00004 ▲3%", Green, "flat"))
+	assert.Contains(t, flat, `aria-label="TRTL: $0.00004 ▲3%"`)
+	assert.Contains(t, flat, `fill="#4c1"`)
+	assert.Contains(t, flat, "linearGradient")
+
+	square := string(Badge("TRTL", "<none>", LightGrey, "flat-square"))
+	assert.Contains(t, square, "&lt;none&gt;")
+	assert.NotContains(t, square, "linearGradient")
+	assert.Nil(t, xml.Unmarshal([]byte(square), new(struct{})))
+}
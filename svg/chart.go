@@ -0,0 +1,167 @@
+// Package svg draws price charts as standalone SVG images that can be embedded anywhere an image can
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+const (
+	marginLeft   = 80
+	marginRight  = 15
+	marginTop    = 30
+	marginBottom = 25
+)
+
+// Chart is the frame a chart is drawn in: its size, title and the time span along the bottom
+type Chart struct {
+	Title  string
+	Width  int
+	Height int
+	From   time.Time
+	To     time.Time
+}
+
+// Line draws the samples as a line chart
+func Line(chart Chart, samples []lib.PriceSample) []byte {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, sample := range samples {
+		low = math.Min(low, sample.Price)
+		high = math.Max(high, sample.Price)
+	}
+
+	var body bytes.Buffer
+	chart.start(&body)
+	if len(samples) == 0 {
+		chart.empty(&body)
+		return chart.end(&body)
+	}
+	scale := chart.axes(&body, low, high)
+
+	body.WriteString(`<polyline fill="none" stroke="#00c000" stroke-width="2" stroke-linejoin="round" points="`)
+	for i, sample := range samples {
+		if i > 0 {
+			body.WriteString(" ")
+		}
+		fmt.Fprintf(&body, "%.1f,%.1f", chart.x(sample.Time), scale(sample.Price))
+	}
+	body.WriteString(`"/>` + "\n")
+	return chart.end(&body)
+}
+
+// Candlestick draws candles of the given period, green when the price closed up and red when it closed down
+func Candlestick(chart Chart, candles []lib.Candle, period time.Duration) []byte {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, candle := range candles {
+		low = math.Min(low, candle.Low)
+		high = math.Max(high, candle.High)
+	}
+
+	var body bytes.Buffer
+	chart.start(&body)
+	if len(candles) == 0 {
+		chart.empty(&body)
+		return chart.end(&body)
+	}
+	scale := chart.axes(&body, low, high)
+
+	candleWidth := math.Max(1, (chart.x(chart.From.Add(period))-chart.x(chart.From))*0.7)
+	for _, candle := range candles {
+		color := "#00c000"
+		if candle.Close < candle.Open {
+			color = "#d03030"
+		}
+		middle := chart.x(candle.Time.Add(period / 2))
+		top, bottom := scale(math.Max(candle.Open, candle.Close)), scale(math.Min(candle.Open, candle.Close))
+		fmt.Fprintf(&body, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n",
+			middle, scale(candle.High), middle, scale(candle.Low), color)
+		fmt.Fprintf(&body, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`+"\n",
+			middle-candleWidth/2, top, candleWidth, math.Max(1, bottom-top), color)
+	}
+	return chart.end(&body)
+}
+
+func (chart Chart) start(body *bytes.Buffer) {
+	fmt.Fprintf(body, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="11">`+"\n",
+		chart.Width, chart.Height, chart.Width, chart.Height)
+	fmt.Fprintf(body, `<rect width="%d" height="%d" fill="#000000"/>`+"\n", chart.Width, chart.Height)
+	fmt.Fprintf(body, `<text x="%d" y="18" fill="#00c000" font-size="13">%s</text>`+"\n", marginLeft, escape(chart.Title))
+}
+
+func (chart Chart) end(body *bytes.Buffer) []byte {
+	body.WriteString("</svg>\n")
+	return body.Bytes()
+}
+
+func (chart Chart) empty(body *bytes.Buffer) {
+	fmt.Fprintf(body, `<text x="%d" y="%d" fill="#00c000" text-anchor="middle">No price history yet</text>`+"\n",
+		chart.Width/2, chart.Height/2)
+}
+
+// axes draws the price and time labels with grid lines and returns the price to y scale
+func (chart Chart) axes(body *bytes.Buffer, low float64, high float64) func(float64) float64 {
+	if high == low {
+		// a flat price still needs some height to be drawn in
+		padding := math.Max(math.Abs(high)*0.01, 1e-12)
+		low, high = low-padding, high+padding
+	}
+	top, bottom := float64(marginTop), float64(chart.Height-marginBottom)
+	scale := func(price float64) float64 {
+		return bottom - (price-low)/(high-low)*(bottom-top)
+	}
+
+	for _, price := range []float64{high, (high + low) / 2, low} {
+		y := scale(price)
+		fmt.Fprintf(body, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#004000"/>`+"\n",
+			marginLeft, y, chart.Width-marginRight, y)
+		fmt.Fprintf(body, `<text x="%d" y="%.1f" fill="#00c000" text-anchor="end">%s</text>`+"\n",
+			marginLeft-5, y+4, priceLabel(price, high-low))
+	}
+
+	layout := "Jan 2 15:04"
+	if chart.To.Sub(chart.From) > 48*time.Hour {
+		layout = "Jan 2"
+	}
+	labelY := chart.Height - 8
+	fmt.Fprintf(body, `<text x="%d" y="%d" fill="#00c000">%s</text>`+"\n", marginLeft, labelY, chart.From.UTC().Format(layout))
+	fmt.Fprintf(body, `<text x="%d" y="%d" fill="#00c000" text-anchor="end">%s UTC</text>`+"\n",
+		chart.Width-marginRight, labelY, chart.To.UTC().Format(layout))
+	return scale
+}
+
+// x is where a time falls along the bottom of the chart
+func (chart Chart) x(at time.Time) float64 {
+	left, right := float64(marginLeft), float64(chart.Width-marginRight)
+	span := chart.To.Sub(chart.From)
+	if span <= 0 {
+		return left
+	}
+	return left + float64(at.Sub(chart.From))/float64(span)*(right-left)
+}
+
+// priceLabel writes a price with enough decimals to tell the labels of a span apart, and no exponent
+func priceLabel(price float64, span float64) string {
+	decimals := 2
+	if span > 0 {
+		decimals = int(math.Ceil(-math.Log10(span))) + 2
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+	if decimals > 12 {
+		decimals = 12
+	}
+	return strconv.FormatFloat(price, 'f', decimals, 64)
+}
+
+func escape(text string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
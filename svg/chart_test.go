@@ -0,0 +1,46 @@
+package svg
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+func TestChartsAreValidSVG(t *testing.T) {
+	from := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	chart := Chart{Title: "TRTL/USD <24h>", Width: 600, Height: 300, From: from, To: from.Add(24 * time.Hour)}
+	samples := []lib.PriceSample{
+		{Time: from.Add(time.Hour), Price: 0.00012},
+		{Time: from.Add(2 * time.Hour), Price: 0.00015},
+		{Time: from.Add(3 * time.Hour), Price: 0.00011},
+	}
+
+	for _, drawn := range [][]byte{
+		Line(chart, samples),
+		Candlestick(chart, lib.Candles(samples, from, time.Hour), time.Hour),
+		Line(chart, nil),
+	} {
+		decoder := xml.NewDecoder(strings.NewReader(string(drawn)))
+		for {
+			_, tokenErr := decoder.Token()
+			if tokenErr != nil {
+				assert.Equal(t, "EOF", tokenErr.Error())
+				break
+			}
+		}
+		assert.Contains(t, string(drawn), "TRTL/USD &lt;24h&gt;")
+	}
+
+	assert.Contains(t, string(Line(chart, samples)), "0.000150")
+	assert.Contains(t, string(Line(chart, nil)), "No price history yet")
+}
+
+func TestPriceLabel(t *testing.T) {
+	assert.Equal(t, "0.0000001200", priceLabel(1.2e-7, 1e-8))
+	assert.Equal(t, "1.50", priceLabel(1.5, 1))
+	assert.Equal(t, "12000", priceLabel(12000, 5000))
+}
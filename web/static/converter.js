@@ -0,0 +1,80 @@
+// Keeps the converter page up to date without reloading it
+// Without JavaScript the form submits and the page refreshes itself instead
+(function () {
+  var refreshEvery = 30 * 1000;
+  var staleAfter = 90 * 1000;
+  var form = document.getElementById("converter");
+  var updated = document.getElementById("updated");
+  var stale = document.getElementById("stale");
+  var lastUpdate = Date.parse(updated.getAttribute("datetime"));
+  var pending = null;
+
+  function query() {
+    var params = new URLSearchParams(new FormData(form));
+    return params.toString();
+  }
+
+  // matches how the server writes numbers, without exponents
+  function formatNumber(number) {
+    return number.toLocaleString("en-US", { useGrouping: false, maximumFractionDigits: 20 });
+  }
+
+  function formatChange(change) {
+    if (change === null) {
+      return "no history yet";
+    }
+    return (change >= 0 ? "+" : "") + change.toFixed(2) + "%";
+  }
+
+  function show(conversion) {
+    document.getElementById("trtl").textContent = formatNumber(conversion.trtl);
+    document.getElementById("value").textContent = formatNumber(conversion.value);
+    document.getElementById("price").textContent = formatNumber(conversion.price);
+    document.getElementById("change").textContent = formatChange(conversion.change24h);
+    var currencies = document.getElementsByClassName("currency");
+    for (var i = 0; i < currencies.length; i++) {
+      currencies[i].textContent = conversion.currency;
+    }
+    updated.setAttribute("datetime", conversion.updated);
+    updated.textContent = conversion.updated;
+    lastUpdate = Date.parse(conversion.updated);
+  }
+
+  function checkStale() {
+    var isStale = Date.now() - lastUpdate > staleAfter;
+    stale.hidden = !isStale;
+    updated.parentNode.classList.toggle("stale", isStale);
+  }
+
+  function refresh() {
+    var params = query();
+    fetch("/?format=json&" + params, { headers: { "Accept": "application/json" } })
+      .then(function (response) {
+        if (!response.ok) {
+          throw new Error(response.status);
+        }
+        return response.json();
+      })
+      .then(function (conversion) {
+        show(conversion);
+        history.replaceState(null, "", "/?" + params);
+      })
+      .catch(function () {})
+      .then(checkStale);
+  }
+
+  // wait for typing to pause before converting
+  function refreshSoon() {
+    clearTimeout(pending);
+    pending = setTimeout(refresh, 300);
+  }
+
+  form.addEventListener("input", refreshSoon);
+  form.addEventListener("change", refreshSoon);
+  form.addEventListener("submit", function (event) {
+    event.preventDefault();
+    refresh();
+  });
+  setInterval(refresh, refreshEvery);
+  setInterval(checkStale, 5 * 1000);
+})();
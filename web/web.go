@@ -0,0 +1,70 @@
+// Package web holds the HTML templates, favicon and static files, embedded so the binary runs from anywhere
+package web
+
+import (
+	"context"
+	"embed"
+	"html/template"
+	"io/fs"
+	"os"
+
+	"github.com/gin-gonic/gin/render"
+	"github.com/pkg/errors"
+	lib "github.com/y4htse/turtle-utils/lib"
+)
+
+//go:embed favicon.ico static templates
+var embedded embed.FS
+
+// Assets are the embedded assets, or the ones in dir when it is set so they can be edited without rebuilding
+// dir is laid out like this package: favicon.ico, static/ and templates/
+func Assets(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	return embedded
+}
+
+// ParseTemplates parses every template in assets
+func ParseTemplates(assets fs.FS) (*template.Template, error) {
+	templates, parseErr := template.ParseFS(assets, "templates/*.tmpl")
+	if parseErr != nil {
+		return nil, errors.Wrap(parseErr, "Problem parsing the templates")
+	}
+	return templates, nil
+}
+
+// HTMLRender renders templates for gin, re-parsing them on every render when Dev is set
+type HTMLRender struct {
+	Assets    fs.FS
+	Dev       bool
+	templates *template.Template
+}
+
+// NewHTMLRender parses the templates up front so mistakes in them are found at startup
+func NewHTMLRender(assets fs.FS, dev bool) (*HTMLRender, error) {
+	templates, parseErr := ParseTemplates(assets)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return &HTMLRender{Assets: assets, Dev: dev, templates: templates}, nil
+}
+
+// Instance is the gin render for the template called name
+// In dev mode a template that no longer parses is logged and the last good one is used
+func (htmlRender *HTMLRender) Instance(name string, data interface{}) render.Render {
+	templates := htmlRender.templates
+	if htmlRender.Dev {
+		reparsed, parseErr := ParseTemplates(htmlRender.Assets)
+		if parseErr != nil {
+			lib.Logger(context.Background()).Error("Problem reloading the templates", "error", parseErr.Error())
+		} else {
+			templates = reparsed
+		}
+	}
+	return render.HTML{
+		Template: templates,
+		Name:     name,
+		Data:     data,
+	}
+}
@@ -0,0 +1,20 @@
+package web
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedAssets(t *testing.T) {
+	templates, parseErr := ParseTemplates(Assets(""))
+	assert.NoError(t, parseErr)
+	assert.NotNil(t, templates.Lookup("index.tmpl"))
+	assert.NotNil(t, templates.Lookup("500.tmpl"))
+
+	_, iconErr := fs.Stat(Assets(""), "favicon.ico")
+	assert.NoError(t, iconErr)
+	_, cssErr := fs.Stat(Assets(""), "static/style.css")
+	assert.NoError(t, cssErr)
+}